@@ -89,6 +89,14 @@ var (
 			Help:      "Number of reconcile loops ending up with no changes on the DNS provider side.",
 		},
 	)
+	controllerUnownedConflicts = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "unowned_conflicts",
+			Help:      "Number of desired records conflicting with existing records not owned by this ExternalDNS.",
+		},
+	)
 	deprecatedRegistryErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: "registry",
@@ -163,6 +171,7 @@ func init() {
 	prometheus.MustRegister(deprecatedRegistryErrors)
 	prometheus.MustRegister(deprecatedSourceErrors)
 	prometheus.MustRegister(controllerNoChangesTotal)
+	prometheus.MustRegister(controllerUnownedConflicts)
 	prometheus.MustRegister(registryARecords)
 	prometheus.MustRegister(registryAAAARecords)
 	prometheus.MustRegister(sourceARecords)
@@ -196,6 +205,8 @@ type Controller struct {
 	ExcludeRecordTypes []string
 	// MinEventSyncInterval is used as window for batching events
 	MinEventSyncInterval time.Duration
+	// PropertyComparators compare the values of provider specific properties by name when calculating the plan
+	PropertyComparators map[string]plan.PropertyComparator
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
@@ -235,17 +246,23 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 	registryFilter := c.Registry.GetDomainFilter()
 
 	plan := &plan.Plan{
-		Policies:       []plan.Policy{c.Policy},
-		Current:        records,
-		Desired:        endpoints,
-		DomainFilter:   endpoint.MatchAllDomainFilters{&c.DomainFilter, &registryFilter},
-		ManagedRecords: c.ManagedRecordTypes,
-		ExcludeRecords: c.ExcludeRecordTypes,
-		OwnerID:        c.Registry.OwnerID(),
+		Policies:            []plan.Policy{c.Policy},
+		Current:             records,
+		Desired:             endpoints,
+		DomainFilter:        endpoint.MatchAllDomainFilters{&c.DomainFilter, &registryFilter},
+		ManagedRecords:      c.ManagedRecordTypes,
+		ExcludeRecords:      c.ExcludeRecordTypes,
+		OwnerID:             c.Registry.OwnerID(),
+		PropertyComparators: c.PropertyComparators,
 	}
 
 	plan = plan.Calculate()
 
+	controllerUnownedConflicts.Set(float64(len(plan.Conflicts)))
+	for _, conflict := range plan.Conflicts {
+		log.Warnf("Skipping %s %s: it conflicts with an existing record not owned by this ExternalDNS", conflict.RecordType, conflict.DNSName)
+	}
+
 	if plan.Changes.HasChanges() {
 		err = c.Registry.ApplyChanges(ctx, plan.Changes)
 		if err != nil {
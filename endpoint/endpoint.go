@@ -44,6 +44,8 @@ const (
 	RecordTypePTR = "PTR"
 	// RecordTypeMX is a RecordType enum value
 	RecordTypeMX = "MX"
+	// RecordTypeCAA is a RecordType enum value
+	RecordTypeCAA = "CAA"
 )
 
 // TTL is a structure defining the TTL of a DNS record
@@ -85,6 +85,9 @@ import (
 	"sigs.k8s.io/external-dns/source"
 )
 
+// webhookEnvPrefix is the prefix of the environment variables configuring the webhook provider
+const webhookEnvPrefix = "EXTERNAL_DNS_WEBHOOK_"
+
 func main() {
 	cfg := externaldns.NewConfig()
 	if err := cfg.ParseFlags(os.Args[1:]); err != nil {
@@ -404,7 +407,13 @@ func main() {
 	case "tencentcloud":
 		p, err = tencentcloud.NewTencentCloudProvider(domainFilter, zoneIDFilter, cfg.TencentCloudConfigFile, cfg.TencentCloudZoneType, cfg.DryRun)
 	case "webhook":
-		p, err = webhook.NewWebhookProvider(cfg.WebhookProviderURL)
+		var opts []webhook.WebhookOption
+		if cfg.WebhookProviderDebugExchanges > 0 {
+			exchanges := webhook.NewExchangeLog(cfg.WebhookProviderDebugExchanges)
+			opts = append(opts, webhook.WebhookWithExchangeLog(exchanges))
+			http.Handle("/debug/webhook", exchanges)
+		}
+		p, err = webhook.NewProviderFromEnv(webhookEnvPrefix, cfg.WebhookProviderURL, opts...)
 	default:
 		log.Fatalf("unknown dns provider: %s", cfg.Provider)
 	}
@@ -454,6 +463,11 @@ func main() {
 		ExcludeRecordTypes:   cfg.ExcludeDNSRecordTypes,
 		MinEventSyncInterval: cfg.MinEventSyncInterval,
 	}
+	if comparing, ok := p.(interface {
+		PropertyComparators() map[string]plan.PropertyComparator
+	}); ok {
+		ctrl.PropertyComparators = comparing.PropertyComparators()
+	}
 
 	if cfg.Once {
 		err := ctrl.RunOnce(ctx)
@@ -215,6 +215,7 @@ type Config struct {
 	WebhookProviderReadTimeout         time.Duration
 	WebhookProviderWriteTimeout        time.Duration
 	WebhookServer                      bool
+	WebhookProviderDebugExchanges      int
 }
 
 var defaultConfig = &Config{
@@ -621,6 +622,8 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("webhook-provider-read-timeout", "[EXPERIMENTAL] The read timeout for the webhook provider in duration format (default: 5s)").Default(defaultConfig.WebhookProviderReadTimeout.String()).DurationVar(&cfg.WebhookProviderReadTimeout)
 	app.Flag("webhook-provider-write-timeout", "[EXPERIMENTAL] The write timeout for the webhook provider in duration format (default: 10s)").Default(defaultConfig.WebhookProviderWriteTimeout.String()).DurationVar(&cfg.WebhookProviderWriteTimeout)
 
+	app.Flag("webhook-provider-debug-exchanges", "[EXPERIMENTAL] Number of the last requests to the webhook provider and their responses served on /debug/webhook of the metrics address, with the sensitive headers redacted (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.WebhookProviderDebugExchanges)).IntVar(&cfg.WebhookProviderDebugExchanges)
+
 	app.Flag("webhook-server", "[EXPERIMENTAL] When enabled, runs as a webhook server instead of a controller. (default: false).").BoolVar(&cfg.WebhookServer)
 
 	_, err := app.Parse(args)
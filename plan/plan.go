@@ -17,7 +17,9 @@ limitations under the License.
 package plan
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -30,6 +32,21 @@ import (
 // PropertyComparator is used in Plan for comparing the previous and current custom annotations.
 type PropertyComparator func(name string, previous string, current string) bool
 
+// JSONPropertyComparator compares the values of properties holding JSON documents, ignoring their formatting
+// and the order of the object keys. Values which are not valid JSON are compared as strings.
+func JSONPropertyComparator(name string, previous string, current string) bool {
+	var p, c interface{}
+	if json.Unmarshal([]byte(previous), &p) != nil || json.Unmarshal([]byte(current), &c) != nil {
+		return previous == current
+	}
+	return reflect.DeepEqual(p, c)
+}
+
+// CaseInsensitivePropertyComparator compares the values of properties ignoring their case
+func CaseInsensitivePropertyComparator(name string, previous string, current string) bool {
+	return strings.EqualFold(previous, current)
+}
+
 // Plan can convert a list of desired and current records to a series of create,
 // update and delete actions.
 type Plan struct {
@@ -50,6 +67,13 @@ type Plan struct {
 	ExcludeRecords []string
 	// OwnerID of records to manage
 	OwnerID string
+	// List of desired records conflicting with current records not owned by OwnerID,
+	// which are left untouched.
+	// Populated after calling Calculate()
+	Conflicts []*endpoint.Endpoint
+	// PropertyComparators compare the values of the provider specific properties by name.
+	// The values of the other properties are compared as strings.
+	PropertyComparators map[string]PropertyComparator
 }
 
 // Changes holds lists of actions to be executed by dns providers
@@ -180,6 +204,7 @@ func (p *Plan) Calculate() *Plan {
 	}
 
 	changes := &Changes{}
+	conflicts := []*endpoint.Endpoint{}
 
 	for key, row := range t.rows {
 		// dns name not taken
@@ -241,6 +266,8 @@ func (p *Plan) Calculate() *Plan {
 
 				if ownersMatch {
 					changes.Create = append(changes.Create, creates...)
+				} else {
+					conflicts = append(conflicts, creates...)
 				}
 			}
 		}
@@ -252,6 +279,11 @@ func (p *Plan) Calculate() *Plan {
 
 	// filter out updates this external dns does not have ownership claim over
 	if p.OwnerID != "" {
+		for _, update := range changes.UpdateNew {
+			if !update.IsOwnedBy(p.OwnerID) {
+				conflicts = append(conflicts, update)
+			}
+		}
 		changes.Delete = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.Delete)
 		changes.UpdateOld = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.UpdateOld)
 		changes.UpdateNew = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.UpdateNew)
@@ -261,6 +293,7 @@ func (p *Plan) Calculate() *Plan {
 		Current:        p.Current,
 		Desired:        p.Desired,
 		Changes:        changes,
+		Conflicts:      conflicts,
 		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
 	}
 
@@ -296,7 +329,7 @@ func (p *Plan) shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint)
 	}
 	for _, c := range current.ProviderSpecific {
 		if d, ok := desiredProperties[c.Name]; ok {
-			if c.Value != d.Value {
+			if !p.propertyValuesEqual(c.Name, c.Value, d.Value) {
 				return true
 			}
 			delete(desiredProperties, c.Name)
@@ -308,6 +341,14 @@ func (p *Plan) shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint)
 	return len(desiredProperties) > 0
 }
 
+// propertyValuesEqual compares the previous and current values of the provider specific property name
+func (p *Plan) propertyValuesEqual(name, previous, current string) bool {
+	if comparator, ok := p.PropertyComparators[name]; ok {
+		return comparator(name, previous, current)
+	}
+	return previous == current
+}
+
 // filterRecordsForPlan removes records that are not relevant to the planner.
 // Currently this just removes TXT records to prevent them from being
 // deleted erroneously by the planner (only the TXT registry should do this.)
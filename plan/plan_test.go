@@ -551,6 +551,36 @@ func (suite *PlanTestSuite) TestExistingOwnerNotMatchingDualStackDesired() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+// TestConflictsWithUnownedRecords validates that desired records colliding with
+// existing records without an ownership claim are reported as conflicts instead of
+// being planned.
+func (suite *PlanTestSuite) TestConflictsWithUnownedRecords() {
+	suite.fooV2Cname.Labels[endpoint.OwnerLabelKey] = "pwner"
+	suite.bar192A.Labels[endpoint.OwnerLabelKey] = "pwner"
+	current := []*endpoint.Endpoint{suite.fooA5, suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.fooV2Cname, suite.bar192A}
+	expectedConflicts := []*endpoint.Endpoint{suite.fooV2Cname, suite.bar192A}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "pwner",
+	}
+
+	calculated := p.Calculate()
+	suite.False(calculated.Changes.HasChanges())
+	validateEntries(suite.T(), calculated.Conflicts, expectedConflicts)
+
+	// records owned by this external dns are not conflicts
+	suite.fooA5.Labels[endpoint.OwnerLabelKey] = "pwner"
+	suite.bar127A.Labels[endpoint.OwnerLabelKey] = "pwner"
+	calculated = p.Calculate()
+	suite.True(calculated.Changes.HasChanges())
+	suite.Empty(calculated.Conflicts)
+}
+
 // TestConflictingCurrentNonConflictingDesired is a bit of a corner case as it would indicate
 // that the provider is not following valid DNS rules or there may be some
 // caching issues. In this case since the desired records are not conflicting
@@ -1085,3 +1115,32 @@ func TestShouldUpdateProviderSpecific(tt *testing.T) {
 		})
 	}
 }
+
+func TestShouldUpdateProviderSpecificWithComparators(tt *testing.T) {
+	comparators := map[string]PropertyComparator{
+		"custom/json":   JSONPropertyComparator,
+		"custom/region": CaseInsensitivePropertyComparator,
+	}
+	for _, test := range []struct {
+		name         string
+		property     string
+		current      string
+		desired      string
+		shouldUpdate bool
+	}{
+		{name: "json formatting changed", property: "custom/json", current: `{"a": 1, "b": [1, 2]}`, desired: `{"b":[1,2],"a":1}`, shouldUpdate: false},
+		{name: "json value changed", property: "custom/json", current: `{"a": 1}`, desired: `{"a": 2}`, shouldUpdate: true},
+		{name: "invalid json unchanged", property: "custom/json", current: `{invalid`, desired: `{invalid`, shouldUpdate: false},
+		{name: "invalid json changed", property: "custom/json", current: `{invalid`, desired: `{"a": 1}`, shouldUpdate: true},
+		{name: "case changed", property: "custom/region", current: "EU-West", desired: "eu-west", shouldUpdate: false},
+		{name: "case insensitive value changed", property: "custom/region", current: "eu-west", desired: "eu-east", shouldUpdate: true},
+		{name: "case changed without comparator", property: "custom/other", current: "EU-West", desired: "eu-west", shouldUpdate: true},
+	} {
+		tt.Run(test.name, func(t *testing.T) {
+			plan := &Plan{PropertyComparators: comparators}
+			current := &endpoint.Endpoint{ProviderSpecific: endpoint.ProviderSpecific{{Name: test.property, Value: test.current}}}
+			desired := &endpoint.Endpoint{ProviderSpecific: endpoint.ProviderSpecific{{Name: test.property, Value: test.desired}}}
+			assert.Equal(t, test.shouldUpdate, plan.shouldUpdateProviderSpecific(desired, current))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// recordTypeALIAS is the ALIAS pseudo record type supported by many DNS providers
+	recordTypeALIAS = "ALIAS"
+	// recordTypeANAME is the ANAME pseudo record type, a synonym of ALIAS
+	recordTypeANAME = "ANAME"
+	// providerSpecificAlias flags an endpoint as an alias, as done by the AWS provider
+	providerSpecificAlias = "alias"
+)
+
+// isAliasType returns true for the ALIAS and ANAME pseudo record types
+func isAliasType(recordType string) bool {
+	switch strings.ToUpper(recordType) {
+	case recordTypeALIAS, recordTypeANAME:
+		return true
+	}
+	return false
+}
+
+// normalizeAlias makes sure ALIAS and ANAME endpoints always carry the alias provider specific property,
+// whether it was set by the source, the webhook, or not at all.
+// The plan compares provider specific properties, so normalizing both the desired and current endpoints
+// prevents updating the same record at every reconciliation.
+func normalizeAlias(endpoints []*endpoint.Endpoint) {
+	for _, e := range endpoints {
+		if !isAliasType(e.RecordType) {
+			continue
+		}
+		e.RecordType = strings.ToUpper(e.RecordType)
+		e.SetProviderSpecificProperty(providerSpecificAlias, "true")
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestAliasRoundTrip(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/records":
+			w.Write([]byte(`[{
+				"dnsName": "example.com",
+				"recordType": "ALIAS",
+				"targets": ["lb.example.net"],
+				"providerSpecific": [{"name": "alias", "value": "true"}]
+			}, {
+				"dnsName": "www.example.com",
+				"recordType": "aname",
+				"targets": ["lb.example.net"]
+			}]`))
+		case "/adjustendpoints":
+			// the webhook returns the endpoints unchanged
+			b, _ := io.ReadAll(r.Body)
+			w.Write(b)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	current, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, current, 2)
+	for _, e := range current {
+		value, ok := e.GetProviderSpecificProperty(providerSpecificAlias)
+		require.True(t, ok)
+		require.Equal(t, "true", value)
+	}
+	require.Equal(t, recordTypeANAME, current[1].RecordType)
+
+	// the source does not know about the alias provider specific property
+	desired, err := provider.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("example.com", recordTypeALIAS, "lb.example.net"),
+		endpoint.NewEndpoint("www.example.com", recordTypeANAME, "lb.example.net"),
+	})
+	require.NoError(t, err)
+
+	p := &plan.Plan{
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{recordTypeALIAS, recordTypeANAME},
+	}
+	changes := p.Calculate().Changes
+	require.False(t, changes.HasChanges(), "unexpected changes: %+v", changes)
+}
+
+func TestAliasTargetChange(t *testing.T) {
+	current := []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", recordTypeALIAS, "lb.example.net")}
+	desired := []*endpoint.Endpoint{endpoint.NewEndpoint("example.com", recordTypeALIAS, "other.example.net")}
+	normalizeAlias(current)
+	normalizeAlias(desired)
+
+	p := &plan.Plan{
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{recordTypeALIAS},
+	}
+	changes := p.Calculate().Changes
+	require.Len(t, changes.UpdateNew, 1)
+	require.Equal(t, endpoint.Targets{"other.example.net"}, changes.UpdateNew[0].Targets)
+}
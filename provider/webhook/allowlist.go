@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithProviderSpecificAllowlist removes the provider specific properties whose name is not in keys
+// from the endpoints sent by ApplyChanges and returned by AdjustEndpoints, logging a warning, for webhooks
+// rejecting the properties they don't know.
+func WebhookWithProviderSpecificAllowlist(keys ...string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.providerSpecificAllowlist = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			p.providerSpecificAllowlist[key] = true
+		}
+	}
+}
+
+// stripProviderSpecific removes the provider specific properties missing from the allowlist from the endpoints
+// of changes, which must be a copy of the plan. It runs after the transformations and enrichers, to cover
+// the properties they add.
+func (p WebhookProvider) stripProviderSpecific(changes *plan.Changes) {
+	if p.providerSpecificAllowlist == nil || changes == nil {
+		return
+	}
+	for _, e := range changesEndpoints(changes) {
+		p.stripEndpointProviderSpecific(e)
+	}
+}
+
+func (p WebhookProvider) stripEndpointProviderSpecific(e *endpoint.Endpoint) {
+	allowed := e.ProviderSpecific[:0]
+	for _, property := range e.ProviderSpecific {
+		if p.providerSpecificAllowlist[property.Name] {
+			allowed = append(allowed, property)
+			continue
+		}
+		log.Warnf("Removing provider specific property %s of %s %s not allowed by the webhook", property.Name, e.RecordType, e.DNSName)
+	}
+	e.ProviderSpecific = allowed
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestProviderSpecificAllowlist(t *testing.T) {
+	var sent plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL,
+		WebhookWithProviderSpecificAllowlist("webhook/zone-id", "webhook/resource"),
+		WebhookWithResourceProviderSpecific())
+	require.NoError(t, err)
+
+	create := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4").
+		WithProviderSpecific("webhook/zone-id", "z1").
+		WithProviderSpecific("aws/evaluate-target-health", "true")
+	create.Labels[endpoint.ResourceLabelKey] = "ingress/default/a"
+	deleted := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4").
+		WithProviderSpecific("unknown", "value")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{create},
+		Delete: []*endpoint.Endpoint{deleted},
+	}))
+
+	require.Len(t, sent.Create, 1)
+	require.Equal(t, endpoint.ProviderSpecific{
+		{Name: "webhook/zone-id", Value: "z1"},
+		{Name: providerSpecificResource, Value: "ingress/default/a"},
+	}, sent.Create[0].ProviderSpecific)
+	require.Len(t, sent.Delete, 1)
+	require.Empty(t, sent.Delete[0].ProviderSpecific)
+	// the plan is left untouched
+	require.Len(t, create.ProviderSpecific, 2)
+	require.Len(t, deleted.ProviderSpecific, 1)
+}
+
+func TestProviderSpecificAllowlistReconcile(t *testing.T) {
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithProviderSpecificAllowlist("webhook/zone-id"))
+	require.NoError(t, err)
+	desired := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4").
+		WithProviderSpecific("webhook/zone-id", "z1").
+		WithProviderSpecific("aws/evaluate-target-health", "true")
+
+	require.True(t, reconcile(t, provider, "", desired))
+	require.Equal(t, endpoint.ProviderSpecific{{Name: "webhook/zone-id", Value: "z1"}}, webhook.records[desired.Key()].ProviderSpecific)
+	// the disallowed properties are removed from the desired endpoints too, so the record is not updated again
+	require.False(t, reconcile(t, provider, "", desired))
+	require.Len(t, desired.ProviderSpecific, 2)
+}
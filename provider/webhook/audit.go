@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+)
+
+// auditIDContextKey is the context key of the Kubernetes audit ID set with ContextWithAuditID
+type auditIDContextKey struct{}
+
+// ContextWithAuditID returns a copy of ctx carrying the Kubernetes audit ID of the API request triggering
+// the reconciliation, which is sent by ApplyChanges when configured with WebhookWithAuditIDHeader
+func ContextWithAuditID(ctx context.Context, auditID string) context.Context {
+	return context.WithValue(ctx, auditIDContextKey{}, auditID)
+}
+
+// WebhookWithAuditIDHeader sends the audit ID set with ContextWithAuditID in the given header on ApplyChanges,
+// so that the DNS changes can be correlated with the API request triggering them
+func WebhookWithAuditIDHeader(header string) WebhookOption {
+	return WebhookWithContextHeader(header, auditIDContextKey{})
+}
+
+// WebhookWithContextHeader sends the value of the context key in the given header on ApplyChanges.
+// The value must be a string or a fmt.Stringer; the header is omitted when the context doesn't carry it.
+func WebhookWithContextHeader(header string, key interface{}) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.contextHeaders == nil {
+			p.contextHeaders = map[string]interface{}{}
+		}
+		p.contextHeaders[header] = key
+	}
+}
+
+// headersFromContext returns the configured headers whose value is carried by ctx
+func (p WebhookProvider) headersFromContext(ctx context.Context) map[string]string {
+	headers := make(map[string]string, len(p.contextHeaders))
+	for header, key := range p.contextHeaders {
+		var value string
+		switch v := ctx.Value(key).(type) {
+		case string:
+			value = v
+		case fmt.Stringer:
+			value = v.String()
+		}
+		if value != "" {
+			headers[header] = value
+		}
+	}
+	return headers
+}
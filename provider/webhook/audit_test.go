@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestAuditIDHeader(t *testing.T) {
+	var auditIDs []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		auditIDs = append(auditIDs, r.Header.Get("X-Audit-Id"))
+		_, ok := r.Header["X-Audit-Id"]
+		require.Equal(t, auditIDs[len(auditIDs)-1] != "", ok)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithAuditIDHeader("X-Audit-Id"))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	require.NoError(t, provider.ApplyChanges(ContextWithAuditID(context.TODO(), "4b3a2c1d"), changes))
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Equal(t, []string{"4b3a2c1d", ""}, auditIDs)
+}
+
+type (
+	requestIDKey struct{}
+	traceIDKey   struct{}
+)
+
+type requestID int
+
+func (id requestID) String() string {
+	return fmt.Sprintf("request-%d", int(id))
+}
+
+func TestHeadersFromContext(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithContextHeader("X-Request-Id", requestIDKey{})(&p)
+	WebhookWithContextHeader("X-Trace-Id", traceIDKey{})(&p)
+
+	require.Empty(t, p.headersFromContext(context.TODO()))
+	ctx := context.WithValue(context.TODO(), requestIDKey{}, requestID(7))
+	// values which are neither strings nor fmt.Stringers are not sent
+	ctx = context.WithValue(ctx, traceIDKey{}, 42)
+	require.Equal(t, map[string]string{"X-Request-Id": "request-7"}, p.headersFromContext(ctx))
+}
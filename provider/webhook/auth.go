@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Authenticator adds credentials to the requests sent to the webhook
+type Authenticator interface {
+	// Authenticate adds the current credentials to req
+	Authenticate(req *http.Request) error
+	// Refresh renews the credentials after the webhook rejected them
+	Refresh(ctx context.Context) error
+}
+
+// WebhookWithAuthenticator authenticates the requests sent to the webhook with a.
+// When the webhook responds with 401 or 403, the credentials are refreshed and the request is retried once.
+func WebhookWithAuthenticator(a Authenticator) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.authenticator = a
+	}
+}
+
+func (p WebhookProvider) authenticate(req *http.Request) error {
+	if p.authenticator == nil {
+		return nil
+	}
+	if err := p.authenticator.Authenticate(req); err != nil {
+		return fmt.Errorf("failed to authenticate request: %w", err)
+	}
+	return nil
+}
+
+func isUnauthorized(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+}
+
+// TokenFileAuthenticator sets the token read from a file, e.g. a projected service account token,
+// as bearer token of the requests. The file is read again when the credentials are refreshed.
+type TokenFileAuthenticator struct {
+	path  string
+	mu    sync.RWMutex
+	token string
+}
+
+// NewTokenFileAuthenticator returns a TokenFileAuthenticator reading the token from path
+func NewTokenFileAuthenticator(path string) (*TokenFileAuthenticator, error) {
+	a := &TokenFileAuthenticator{path: path}
+	if err := a.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate sets the bearer token of req
+func (a *TokenFileAuthenticator) Authenticate(req *http.Request) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+// Refresh reads the token file again
+func (a *TokenFileAuthenticator) Refresh(_ context.Context) error {
+	b, err := os.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = strings.TrimSpace(string(b))
+	return nil
+}
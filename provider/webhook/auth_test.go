@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenServer returns a webhook server accepting the records requests authenticated with token
+func newTokenServer(token string, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		atomic.AddInt32(calls, 1)
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+}
+
+func TestCredentialRefresh(t *testing.T) {
+	var calls int32
+	svr := newTokenServer("new-token", &calls)
+	defer svr.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("old-token\n"), 0o600))
+	authenticator, err := NewTokenFileAuthenticator(tokenFile)
+	require.NoError(t, err)
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte("new-token\n"), 0o600))
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// the refreshed token is used for the next requests
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestCredentialRefreshRejected(t *testing.T) {
+	var calls int32
+	svr := newTokenServer("other-token", &calls)
+	defer svr.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("old-token"), 0o600))
+	authenticator, err := NewTokenFileAuthenticator(tokenFile)
+	require.NoError(t, err)
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	_, err = provider.Records(context.TODO())
+	require.ErrorIs(t, err, ErrWebhookUnauthorized)
+	require.EqualError(t, err, "webhook unauthorized: code 401 after refreshing credentials")
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
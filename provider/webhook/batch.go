@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// batching bounds the changes sent by a single ApplyChanges request
+type batching struct {
+	maxEndpoints int
+	maxBytes     int
+}
+
+// WebhookWithBatchSize splits the changes sent by ApplyChanges into requests of at most maxEndpoints endpoints,
+// an update counting as two endpoints. Unlike WebhookWithMaxEndpoints, all the changes are still applied.
+func WebhookWithBatchSize(maxEndpoints int) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.batching == nil {
+			p.batching = &batching{}
+		}
+		p.batching.maxEndpoints = maxEndpoints
+	}
+}
+
+// WebhookWithBatchBytes splits the changes sent by ApplyChanges into requests whose body is at most maxBytes,
+// before compression and including the zero TTLs and the envelope, for webhooks limiting the size of the request
+// bodies. It accounts for endpoints with many targets better than WebhookWithBatchSize, with which it can be combined.
+// An endpoint larger than maxBytes on its own is sent alone.
+func WebhookWithBatchBytes(maxBytes int) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.batching == nil {
+			p.batching = &batching{}
+		}
+		p.batching.maxBytes = maxBytes
+	}
+}
+
+// batches splits changes into the batches of at most maxEndpoints endpoints and maxBytes bytes, keeping the update
+// pairs together. The size of a batch is an upper bound of the size of its encoding with encode.
+func (b *batching) batches(encode payloadEncoder, changes *plan.Changes) ([]*plan.Changes, error) {
+	if b == nil || changes == nil {
+		return []*plan.Changes{changes}, nil
+	}
+	units, ok := changeUnits(changes)
+	if !ok {
+		return nil, fmt.Errorf("can't split %d old and %d new endpoints of updates", len(changes.UpdateOld), len(changes.UpdateNew))
+	}
+	overhead := 0
+	if b.maxBytes > 0 {
+		var err error
+		if overhead, err = encodedChangesSize(encode, &plan.Changes{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var batches []*plan.Changes
+	var current []changeUnit
+	endpoints, size := 0, overhead
+	for _, u := range units {
+		unitEndpoints, unitSize := 0, 0
+		for _, e := range []*endpoint.Endpoint{u.old, u.new} {
+			if e == nil {
+				continue
+			}
+			unitEndpoints++
+			if b.maxBytes > 0 {
+				s, err := encodedEndpointSize(encode, e)
+				if err != nil {
+					return nil, err
+				}
+				unitSize += s
+			}
+		}
+		exceedsCount := b.maxEndpoints > 0 && endpoints+unitEndpoints > b.maxEndpoints
+		exceedsBytes := b.maxBytes > 0 && size+unitSize > b.maxBytes
+		if len(current) > 0 && (exceedsCount || exceedsBytes) {
+			batches = append(batches, unitsChanges(current))
+			current, endpoints, size = nil, 0, overhead
+		}
+		current = append(current, u)
+		endpoints += unitEndpoints
+		size += unitSize
+	}
+	if len(current) > 0 || len(batches) == 0 {
+		batches = append(batches, unitsChanges(current))
+	}
+	return batches, nil
+}
+
+// payloadEncoder encodes changes to the body of an ApplyChanges request, before compression
+type payloadEncoder func(*plan.Changes) ([]byte, error)
+
+// encodedEndpointSize returns the size added by e to the encoding of a list of endpoints, including its separator
+func encodedEndpointSize(encode payloadEncoder, e *endpoint.Endpoint) (int, error) {
+	one, err := encodedChangesSize(encode, &plan.Changes{Create: []*endpoint.Endpoint{e}})
+	if err != nil {
+		return 0, err
+	}
+	two, err := encodedChangesSize(encode, &plan.Changes{Create: []*endpoint.Endpoint{e, e}})
+	if err != nil {
+		return 0, err
+	}
+	return two - one, nil
+}
+
+func encodedChangesSize(encode payloadEncoder, changes *plan.Changes) (int, error) {
+	b, err := encode(changes)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// batched returns post sending the changes in batches, stopping at the first failure
+func (p WebhookProvider) batched(post func(context.Context, *plan.Changes, map[string]string) error) func(context.Context, *plan.Changes, map[string]string) error {
+	return func(ctx context.Context, changes *plan.Changes, extraHeaders map[string]string) error {
+		batches, err := p.batching.batches(p.encodeChanges, changes)
+		if err != nil {
+			return err
+		}
+		for i, batch := range batches {
+			if err := post(ctx, batch, extraHeaders); err != nil {
+				if len(batches) > 1 {
+					return fmt.Errorf("failed to apply batch %d of %d: %w", i+1, len(batches), err)
+				}
+				return err
+			}
+		}
+		return nil
+	}
+}
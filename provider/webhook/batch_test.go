@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestBatchBytes(t *testing.T) {
+	large := endpoint.NewEndpoint("large.example.com", endpoint.RecordTypeA)
+	for i := 0; i < 50; i++ {
+		large.Targets = append(large.Targets, fmt.Sprintf("10.0.0.%d", i))
+	}
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			large,
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "4.3.2.1")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+
+	var bodies []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	// the large endpoint alone fills a request
+	overhead, err := encodedChangesSize(WebhookProvider{}.encodeChanges, &plan.Changes{})
+	require.NoError(t, err)
+	largeSize, err := encodedEndpointSize(WebhookProvider{}.encodeChanges, large)
+	require.NoError(t, err)
+	maxBytes := overhead + largeSize
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithBatchBytes(maxBytes))
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	require.Len(t, bodies, 3)
+	for _, body := range bodies {
+		require.LessOrEqual(t, len(body), maxBytes)
+	}
+	require.Contains(t, bodies[0], `"a.example.com"`)
+	require.Contains(t, bodies[0], `"b.example.com"`)
+	require.Contains(t, bodies[1], `"large.example.com"`)
+	require.NotContains(t, bodies[1], `"a.example.com"`)
+	// the update pair stays in the same request
+	require.Contains(t, bodies[2], `"1.2.3.4"]`)
+	require.Contains(t, bodies[2], `"4.3.2.1"`)
+	require.Contains(t, bodies[2], `"d.example.com"`)
+}
+
+func TestBatchSize(t *testing.T) {
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "4.3.2.1")},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+	batches, err := (&batching{maxEndpoints: 2}).batches(WebhookProvider{}.encodeChanges, changes)
+	require.NoError(t, err)
+	require.Equal(t, []*plan.Changes{
+		{Create: changes.Create},
+		{UpdateOld: changes.UpdateOld, UpdateNew: changes.UpdateNew},
+		{Delete: changes.Delete},
+	}, batches)
+
+	batches, err = (*batching)(nil).batches(WebhookProvider{}.encodeChanges, changes)
+	require.NoError(t, err)
+	require.Equal(t, []*plan.Changes{changes}, batches)
+}
+
+func TestBatchBytesEnvelopeZeroTTL(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+
+	var bodies []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	opts := []WebhookOption{WebhookWithEnvelope(Envelope{ControllerID: "default"}), WebhookWithZeroTTL(ZeroTTLSend)}
+	// the limit fits two endpoints with the envelope and the zero TTLs
+	unbatched, err := NewWebhookProvider(svr.URL, opts...)
+	require.NoError(t, err)
+	overhead, err := encodedChangesSize(unbatched.encodeChanges, &plan.Changes{})
+	require.NoError(t, err)
+	size, err := encodedEndpointSize(unbatched.encodeChanges, changes.Create[0])
+	require.NoError(t, err)
+	maxBytes := overhead + 2*size
+
+	provider, err := NewWebhookProvider(svr.URL, append(opts, WebhookWithBatchBytes(maxBytes))...)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	require.Len(t, bodies, 2)
+	for _, body := range bodies {
+		require.LessOrEqual(t, len(body), maxBytes)
+		require.Contains(t, body, `"recordTTL":0`)
+		require.Contains(t, body, `"controllerId":"default"`)
+	}
+	all := strings.Join(bodies, "")
+	for _, e := range changes.Create {
+		require.Contains(t, all, `"`+e.DNSName+`"`)
+	}
+}
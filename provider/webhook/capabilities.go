@@ -0,0 +1,46 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import "sigs.k8s.io/external-dns/endpoint"
+
+// capabilities are the optional features advertised by the webhook during the negotiation.
+// They are read from the "capabilities" field of the negotiation response, next to the serialized domain filter,
+// so that webhooks not advertising any keep working unchanged.
+type capabilities struct {
+	// Transactions is true when the webhook supports applying changes within a transaction
+	Transactions bool `json:"transactions,omitempty"`
+	// Incremental is true when the webhook can return only the records changed since a token
+	Incremental bool `json:"incremental,omitempty"`
+	// OwnerFilter is true when the webhook can return only the records of an owner
+	OwnerFilter bool `json:"ownerFilter,omitempty"`
+	// MinTTL is the minimum TTL supported by the webhook, enforced on the endpoints sent by ApplyChanges
+	MinTTL endpoint.TTL `json:"minTTL,omitempty"`
+	// RecordTypes are the record types supported by the webhook, the endpoints of other types being
+	// dropped from ApplyChanges. All the record types are supported when empty.
+	RecordTypes []string `json:"recordTypes,omitempty"`
+}
+
+// negotiationResponse is the part of the negotiation response which is not the domain filter
+type negotiationResponse struct {
+	Capabilities capabilities `json:"capabilities,omitempty"`
+	// DefaultTTLs are the default TTLs of the endpoints without TTL, by zone
+	DefaultTTLs map[string]endpoint.TTL `json:"defaultTTLs,omitempty"`
+	// RegexInclude and RegexExclude are set when the domain filter of the webhook is a regex filter
+	RegexInclude string `json:"regexInclude,omitempty"`
+	RegexExclude string `json:"regexExclude,omitempty"`
+}
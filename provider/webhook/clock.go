@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import "time"
+
+// Clock provides the time to the webhook provider, e.g. to wait between retries.
+// It also implements the clock of the backoff package.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WebhookWithClock replaces the real clock used for the retries, the propagation polling and the sync status,
+// so that tests can control the time instead of waiting
+func WebhookWithClock(c Clock) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.clock = c
+	}
+}
+
+// clockOrReal returns the configured clock, or the real clock
+func (p WebhookProvider) clockOrReal() Clock {
+	if p.clock == nil {
+		return realClock{}
+	}
+	return p.clock
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances immediately by the duration waited for, recording the waits
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRetryBackoffWithFakeClock(t *testing.T) {
+	var calls int32
+	svr := newFailingServer(4, &calls)
+	defer svr.Close()
+
+	clock := newFakeClock()
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(5), WebhookWithClock(clock))
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, int32(5), atomic.LoadInt32(&calls))
+	require.Less(t, time.Since(start), time.Second)
+
+	// the default exponential backoff starts at 500ms, multiplied by 1.5 with a randomization of 50%
+	require.Len(t, clock.waits, 4)
+	expected := 500 * time.Millisecond
+	for _, wait := range clock.waits {
+		require.GreaterOrEqual(t, wait, expected/2)
+		require.LessOrEqual(t, wait, expected*3/2)
+		expected = expected * 3 / 2
+	}
+}
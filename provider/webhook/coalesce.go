@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithApplyCoalescing coalesces the calls to ApplyChanges made while another one is in flight.
+// Calls with the same changes as a pending call share its result instead of sending the changes again,
+// the others are queued and applied one at a time.
+func WebhookWithApplyCoalescing() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.coalescer = &applyCoalescer{}
+	}
+}
+
+// applyCoalescer deduplicates identical pending applies and serializes the others
+type applyCoalescer struct {
+	group singleflight.Group
+	mu    sync.Mutex
+}
+
+// do calls apply unless a call with the same changes is pending, in which case its result is returned
+func (c *applyCoalescer) do(changes *plan.Changes, apply func() error) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	key := sha256.Sum256(b)
+	_, err, shared := c.group.Do(string(key[:]), func() (interface{}, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, apply()
+	})
+	if shared {
+		log.Debugf("Coalesced identical apply of changes to the webhook")
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestApplyCoalescing(t *testing.T) {
+	var posts, inFlight, maxInFlight int32
+	received := make(chan struct{}, 3)
+	release := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		atomic.AddInt32(&posts, 1)
+		if n := atomic.AddInt32(&inFlight, 1); n > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, n)
+		}
+		received <- struct{}{}
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithApplyCoalescing())
+	require.NoError(t, err)
+
+	identical := func() *plan.Changes {
+		return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	}
+	different := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	apply := func(i int, changes *plan.Changes) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = provider.ApplyChanges(context.TODO(), changes)
+		}()
+	}
+	apply(0, identical())
+	<-received
+	// both calls arrive while the first apply is in flight
+	apply(1, identical())
+	apply(2, different)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&posts))
+	require.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+}
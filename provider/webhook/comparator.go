@@ -0,0 +1,39 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithPropertyComparator compares the values of the provider specific property name with comparator
+// when calculating the plan, e.g. plan.JSONPropertyComparator for properties the webhook normalizes.
+// The values of the other properties are compared as strings.
+func WebhookWithPropertyComparator(name string, comparator plan.PropertyComparator) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.propertyComparators == nil {
+			p.propertyComparators = map[string]plan.PropertyComparator{}
+		}
+		p.propertyComparators[name] = comparator
+	}
+}
+
+// PropertyComparators returns the comparators of the provider specific properties configured with
+// WebhookWithPropertyComparator, to be used by the plan
+func (p WebhookProvider) PropertyComparators() map[string]plan.PropertyComparator {
+	return p.propertyComparators
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestPropertyComparator(t *testing.T) {
+	var paths []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/adjustendpoints":
+			io.Copy(w, r.Body)
+		case "/records":
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// the webhook normalizes the JSON document
+			w.Write([]byte(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"],"providerSpecific":[{"name":"webhook/config","value":"{\"a\":1,\"b\":2}"}]}]`))
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPropertyComparator("webhook/config", plan.JSONPropertyComparator))
+	require.NoError(t, err)
+
+	current, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	desired, err := provider.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("webhook/config", `{"b": 2, "a": 1}`),
+	})
+	require.NoError(t, err)
+
+	changes := (&plan.Plan{
+		Current:             current,
+		Desired:             desired,
+		Policies:            []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords:      []string{endpoint.RecordTypeA},
+		PropertyComparators: provider.PropertyComparators(),
+	}).Calculate().Changes
+	require.False(t, changes.HasChanges())
+	// the values are compared locally
+	require.Equal(t, []string{"GET /", "GET /records", "POST /adjustendpoints"}, paths)
+}
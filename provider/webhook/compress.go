@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+const contentEncodingHeader = "Content-Encoding"
+
+// requestCompression compresses the request bodies larger than minBytes
+type requestCompression struct {
+	minBytes int
+}
+
+// WebhookWithRequestCompression gzips the body of the ApplyChanges requests larger than minBytes,
+// setting the Content-Encoding header. Smaller bodies, for which compression is not worth it, are sent as is.
+func WebhookWithRequestCompression(minBytes int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.compression = &requestCompression{minBytes: minBytes}
+	}
+}
+
+// compress returns the body to send and its content encoding, empty if the body is not compressed
+func (c *requestCompression) compress(body []byte) ([]byte, string, error) {
+	if c == nil || len(body) <= c.minBytes {
+		return body, "", nil
+	}
+	b := new(bytes.Buffer)
+	w := gzip.NewWriter(b)
+	if _, err := w.Write(body); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), "gzip", nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestRequestCompression(t *testing.T) {
+	var encoding string
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		encoding = r.Header.Get(contentEncodingHeader)
+		var body io.Reader = r.Body
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+		applied = plan.Changes{}
+		require.NoError(t, json.NewDecoder(body).Decode(&applied))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRequestCompression(1024))
+	require.NoError(t, err)
+
+	small := &plan.Changes{Create: staticEndpoints(1)}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), small))
+	require.Empty(t, encoding)
+	require.Len(t, applied.Create, 1)
+
+	large := &plan.Changes{Create: staticEndpoints(100)}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), large))
+	require.Equal(t, "gzip", encoding)
+	require.Len(t, applied.Create, 100)
+	require.Equal(t, endpoint.Targets{"1.2.3.4"}, applied.Create[99].Targets)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithRecordTypePriority resolves the conflicts between a CNAME and other record types created or updated
+// for the same DNS name, which webhooks reject, by keeping the endpoints of the type coming first in types
+// and dropping the others with a warning. Types missing from types come last. The records of the dropped updates
+// are deleted, so that they don't conflict with the kept endpoints.
+// The plan already drops a CNAME desired along with other types for the same DNS name and set identifier,
+// so the priority decides the conflicts between endpoints of different set identifiers.
+func WebhookWithRecordTypePriority(types ...string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.recordTypePriority = map[string]int{}
+		for i, t := range types {
+			p.recordTypePriority[strings.ToUpper(t)] = i
+		}
+	}
+}
+
+// priority returns the rank of the record type, lower being preferred
+func (p WebhookProvider) priority(recordType string) int {
+	if rank, ok := p.recordTypePriority[recordType]; ok {
+		return rank
+	}
+	return len(p.recordTypePriority)
+}
+
+// resolveTypeConflicts drops the created and updated endpoints conflicting with a CNAME of higher priority,
+// or the CNAME if another type has the higher priority, deleting the records of the dropped updates
+func (p WebhookProvider) resolveTypeConflicts(changes *plan.Changes) *plan.Changes {
+	if changes == nil || p.recordTypePriority == nil {
+		return changes
+	}
+
+	types := map[string][]string{}
+	for _, e := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		name := normalizeName(e.DNSName)
+		types[name] = append(types[name], e.RecordType)
+	}
+	// kept is the record type kept for the names with conflicts
+	kept := map[string]string{}
+	for name, recordTypes := range types {
+		hasCNAME, hasOther := false, false
+		for _, t := range recordTypes {
+			if t == endpoint.RecordTypeCNAME {
+				hasCNAME = true
+			} else {
+				hasOther = true
+			}
+		}
+		if !hasCNAME || !hasOther {
+			continue
+		}
+		best := recordTypes[0]
+		for _, t := range recordTypes[1:] {
+			if p.priority(t) < p.priority(best) {
+				best = t
+			}
+		}
+		kept[name] = best
+	}
+	if len(kept) == 0 {
+		return changes
+	}
+
+	keep := func(e *endpoint.Endpoint) bool {
+		best, ok := kept[normalizeName(e.DNSName)]
+		if !ok || e.RecordType == best || e.RecordType != endpoint.RecordTypeCNAME && best != endpoint.RecordTypeCNAME {
+			return true
+		}
+		log.Warnf("Dropping %s endpoint %s conflicting with the %s endpoint of the same name", e.RecordType, e.DNSName, best)
+		return false
+	}
+	filtered := &plan.Changes{
+		Create: filterEndpoints(changes.Create, keep),
+		// the full slice expression keeps the deletions of changes from being appended to
+		Delete: changes.Delete[:len(changes.Delete):len(changes.Delete)],
+	}
+	for i := range changes.UpdateNew {
+		if i >= len(changes.UpdateOld) {
+			continue
+		}
+		if keep(changes.UpdateNew[i]) {
+			filtered.UpdateOld = append(filtered.UpdateOld, changes.UpdateOld[i])
+			filtered.UpdateNew = append(filtered.UpdateNew, changes.UpdateNew[i])
+			continue
+		}
+		filtered.Delete = append(filtered.Delete, changes.UpdateOld[i])
+	}
+	return filtered
+}
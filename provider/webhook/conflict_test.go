@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestRecordTypePriority(t *testing.T) {
+	// the plan doesn't resolve the conflicts between endpoints of different set identifiers
+	desired := func() []*endpoint.Endpoint {
+		return []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4").WithSetIdentifier("green"),
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeCNAME, "new.example.com").WithSetIdentifier("blue"),
+			endpoint.NewEndpoint("bar.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		}
+	}
+	current := func() []*endpoint.Endpoint {
+		return []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeCNAME, "old.example.com").WithSetIdentifier("blue"),
+		}
+	}
+	names := func(endpoints []*endpoint.Endpoint) []string {
+		var names []string
+		for _, e := range endpoints {
+			names = append(names, e.RecordType+" "+e.DNSName)
+		}
+		return names
+	}
+
+	for _, tc := range []struct {
+		name     string
+		priority []string
+		created  []string
+		updated  []string
+		deleted  []string
+	}{
+		{
+			name:     "A over CNAME",
+			priority: []string{"A", "CNAME"},
+			created:  []string{"A bar.example.com", "A foo.example.com"},
+			// the CNAME whose update is dropped is deleted
+			deleted: []string{"CNAME foo.example.com"},
+		},
+		{
+			name:     "CNAME over A",
+			priority: []string{"cname", "a"},
+			created:  []string{"A bar.example.com"},
+			updated:  []string{"CNAME foo.example.com"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var applied plan.Changes
+			svr := newApplyServer(t, &applied)
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithRecordTypePriority(tc.priority...))
+			require.NoError(t, err)
+			p := &plan.Plan{
+				Policies:       []plan.Policy{&plan.SyncPolicy{}},
+				Current:        current(),
+				Desired:        desired(),
+				ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+			}
+			changes := p.Calculate().Changes
+			require.Len(t, changes.Create, 2)
+			require.Len(t, changes.UpdateNew, 1)
+			require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+			require.ElementsMatch(t, tc.created, names(applied.Create))
+			require.Equal(t, tc.updated, names(applied.UpdateNew))
+			require.Equal(t, tc.updated, names(applied.UpdateOld))
+			require.Equal(t, tc.deleted, names(applied.Delete))
+		})
+	}
+
+	// without priority the conflicting changes are sent as they are
+	p := WebhookProvider{}
+	changes := &plan.Changes{Create: desired()}
+	require.Same(t, changes, p.resolveTypeConflicts(changes))
+}
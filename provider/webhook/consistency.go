@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// consistencyCheck remembers the endpoints applied by the last ApplyChanges call,
+// to verify that the next Records call returns them in the same form
+type consistencyCheck struct {
+	mu       sync.Mutex
+	expected []*endpoint.Endpoint
+	limiter  *rate.Limiter
+}
+
+// WebhookWithConsistencyCheck warns when the endpoints created or updated by ApplyChanges are returned
+// in a different form by the next Records call, e.g. with normalized targets, which makes external-dns
+// apply them again at every reconciliation. The warning is logged at most once per interval.
+func WebhookWithConsistencyCheck(interval time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.consistency = &consistencyCheck{limiter: rate.NewLimiter(rate.Every(interval), 1)}
+	}
+}
+
+// applied remembers the endpoints created or updated by changes
+func (c *consistencyCheck) applied(changes *plan.Changes) {
+	if c == nil || changes == nil {
+		return
+	}
+	expected := make([]*endpoint.Endpoint, 0, len(changes.Create)+len(changes.UpdateNew))
+	expected = append(expected, copyEndpoints(changes.Create)...)
+	expected = append(expected, copyEndpoints(changes.UpdateNew)...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expected = expected
+}
+
+// verify compares the endpoints of the last apply with records, logging the mismatches
+func (c *consistencyCheck) verify(records []*endpoint.Endpoint) []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	expected := c.expected
+	c.expected = nil
+	c.mu.Unlock()
+
+	mismatches := endpointMismatches(expected, records)
+	if len(mismatches) > 0 && c.limiter.Allow() {
+		for _, m := range mismatches {
+			log.Warnf("Records do not match the applied changes, the endpoint will be applied again: %s", m)
+		}
+	}
+	return mismatches
+}
+
+func endpointMismatches(expected, records []*endpoint.Endpoint) []string {
+	byKey := make(map[endpoint.EndpointKey]*endpoint.Endpoint, len(records))
+	for _, r := range records {
+		byKey[r.Key()] = r
+	}
+	var mismatches []string
+	for _, e := range expected {
+		r, ok := byKey[e.Key()]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("endpoint %s %s was applied but is missing from the records", e.DNSName, e.RecordType))
+		case !r.Targets.Same(e.Targets):
+			mismatches = append(mismatches, fmt.Sprintf("endpoint %s %s was applied with targets %v but has targets %v", e.DNSName, e.RecordType, e.Targets, r.Targets))
+		case e.RecordTTL.IsConfigured() && r.RecordTTL != e.RecordTTL:
+			mismatches = append(mismatches, fmt.Sprintf("endpoint %s %s was applied with TTL %d but has TTL %d", e.DNSName, e.RecordType, e.RecordTTL, r.RecordTTL))
+		}
+	}
+	return mismatches
+}
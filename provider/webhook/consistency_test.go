@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestEndpointMismatches(t *testing.T) {
+	expected := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("same.example.com", endpoint.RecordTypeA, 300, "1.2.3.4", "5.6.7.8"),
+		endpoint.NewEndpoint("missing.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("targets.example.com", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpointWithTTL("ttl.example.com", endpoint.RecordTypeA, 300, "1.2.3.4"),
+		endpoint.NewEndpoint("default-ttl.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}
+	records := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("same.example.com", endpoint.RecordTypeA, 300, "5.6.7.8", "1.2.3.4"),
+		endpoint.NewEndpoint("missing.example.com", endpoint.RecordTypeAAAA, "::1"),
+		endpoint.NewEndpoint("targets.example.com", endpoint.RecordTypeCNAME, "lb.eu-west-1.example.net"),
+		endpoint.NewEndpointWithTTL("ttl.example.com", endpoint.RecordTypeA, 3600, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("default-ttl.example.com", endpoint.RecordTypeA, 3600, "1.2.3.4"),
+	}
+	require.Equal(t, []string{
+		"endpoint missing.example.com A was applied but is missing from the records",
+		"endpoint targets.example.com CNAME was applied with targets lb.example.net but has targets lb.eu-west-1.example.net",
+		"endpoint ttl.example.com A was applied with TTL 300 but has TTL 3600",
+	}, endpointMismatches(expected, records))
+}
+
+func TestConsistencyCheckRateLimited(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	p := WebhookProvider{}
+	WebhookWithConsistencyCheck(time.Hour)(&p)
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	for i := 0; i < 3; i++ {
+		p.consistency.applied(changes)
+		require.Len(t, p.consistency.verify(nil), 1)
+	}
+	warnings := 0
+	for _, e := range hook.AllEntries() {
+		if e.Level == log.WarnLevel {
+			warnings++
+		}
+	}
+	require.Equal(t, 1, warnings)
+
+	// the applied endpoints are only verified by the next Records call
+	require.Empty(t, p.consistency.verify(nil))
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxExchangeBodySize limits how much of the bodies of an exchange is kept
+	maxExchangeBodySize = 64 << 10
+	redactedValue       = "REDACTED"
+)
+
+// redactedHeaders are the headers whose values are never kept in an exchange
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", signatureHeader}
+
+// Exchange is a request sent to the webhook and its response
+type Exchange struct {
+	Time            time.Time   `json:"time"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	RequestBody     string      `json:"requestBody,omitempty"`
+	Status          int         `json:"status,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    string      `json:"responseBody,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// ExchangeLog keeps the last requests sent to the webhook and their responses, with the sensitive headers redacted.
+// It is an http.Handler serving them as JSON, oldest first, e.g. on a debug endpoint.
+type ExchangeLog struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+	full      bool
+}
+
+// NewExchangeLog returns an ExchangeLog keeping the last size exchanges
+func NewExchangeLog(size int) *ExchangeLog {
+	if size < 1 {
+		size = 1
+	}
+	return &ExchangeLog{exchanges: make([]Exchange, size)}
+}
+
+// WebhookWithExchangeLog records the requests sent to the webhook and their responses in l
+func WebhookWithExchangeLog(l *ExchangeLog) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.client.Transport = &exchangeTransport{next: p.client.Transport, log: l}
+	}
+}
+
+func (l *ExchangeLog) add(e Exchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exchanges[l.next] = e
+	l.next = (l.next + 1) % len(l.exchanges)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Exchanges returns the recorded exchanges, oldest first
+func (l *ExchangeLog) Exchanges() []Exchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Exchange{}, l.exchanges[:l.next]...)
+	}
+	return append(append([]Exchange{}, l.exchanges[l.next:]...), l.exchanges[:l.next]...)
+}
+
+func (l *ExchangeLog) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(contentTypeHeader, "application/json")
+	json.NewEncoder(w).Encode(l.Exchanges())
+}
+
+// exchangeTransport records the exchanges going through the next round tripper
+type exchangeTransport struct {
+	next http.RoundTripper
+	log  *ExchangeLog
+}
+
+func (t *exchangeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := Exchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            req.URL.Redacted(),
+		RequestHeaders: redact(req.Header),
+	}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			e.RequestBody = readExchangeBody(body)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		t.log.add(e)
+		return nil, err
+	}
+	e.Status = resp.StatusCode
+	e.ResponseHeaders = redact(resp.Header)
+	if resp.Body != nil {
+		// only the start of the body is kept, the rest is streamed to the caller unread
+		b, err := io.ReadAll(io.LimitReader(resp.Body, maxExchangeBodySize))
+		if err != nil {
+			e.Error = err.Error()
+		}
+		e.ResponseBody = string(b)
+		resp.Body = &replayedBody{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body}
+	}
+	t.log.add(e)
+	return resp, nil
+}
+
+func readExchangeBody(body io.ReadCloser) string {
+	defer body.Close()
+	b, _ := io.ReadAll(io.LimitReader(body, maxExchangeBodySize))
+	return string(b)
+}
+
+// replayedBody is a response body whose start was already read
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// redact returns a copy of header with the values of the sensitive headers replaced
+func redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, redactedValue)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestExchangeLogRingBuffer(t *testing.T) {
+	l := NewExchangeLog(3)
+	require.Empty(t, l.Exchanges())
+
+	for i := 0; i < 5; i++ {
+		l.add(Exchange{URL: fmt.Sprintf("/%d", i)})
+	}
+	exchanges := l.Exchanges()
+	require.Len(t, exchanges, 3)
+	require.Equal(t, "/2", exchanges[0].URL)
+	require.Equal(t, "/3", exchanges[1].URL)
+	require.Equal(t, "/4", exchanges[2].URL)
+}
+
+func TestExchangeLog(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret-token"), 0o600))
+	authenticator, err := NewTokenFileAuthenticator(tokenFile)
+	require.NoError(t, err)
+
+	exchanges := NewExchangeLog(10)
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithExchangeLog(exchanges), WebhookWithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	// the debug handler serves the negotiation and the apply
+	rec := httptest.NewRecorder()
+	exchanges.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/webhook", nil))
+	var served []Exchange
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 2)
+
+	apply := served[1]
+	require.Equal(t, http.MethodPost, apply.Method)
+	require.Equal(t, svr.URL+"/records", apply.URL)
+	require.Contains(t, apply.RequestBody, `"a.example.com"`)
+	require.Equal(t, http.StatusNoContent, apply.Status)
+	require.Equal(t, redactedValue, apply.RequestHeaders.Get("Authorization"))
+	require.Equal(t, redactedValue, apply.ResponseHeaders.Get("Set-Cookie"))
+	require.NotContains(t, rec.Body.String(), "secret")
+}
+
+func TestExchangeLogLargeResponse(t *testing.T) {
+	records := make([]*endpoint.Endpoint, 0, 2000)
+	for i := 0; i < cap(records); i++ {
+		records = append(records, endpoint.NewEndpoint(fmt.Sprintf("a%d.example.com", i), endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		json.NewEncoder(w).Encode(records)
+	}))
+	defer svr.Close()
+
+	u, err := url.Parse(svr.URL)
+	require.NoError(t, err)
+	u.User = url.UserPassword("user", "secret")
+
+	exchanges := NewExchangeLog(10)
+	provider, err := NewWebhookProvider(u.String(), WebhookWithExchangeLog(exchanges))
+	require.NoError(t, err)
+
+	// the response is still decoded whole
+	got, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, got, len(records))
+
+	served := exchanges.Exchanges()
+	require.Len(t, served, 2)
+	require.Len(t, served[1].ResponseBody, maxExchangeBodySize)
+	require.NotContains(t, served[1].URL, "secret")
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// decodeLimits bounds the JSON documents decoded from the webhook responses
+type decodeLimits struct {
+	maxBytes    int64
+	maxDepth    int
+	maxElements int
+}
+
+// WebhookWithDecodeLimits rejects the Records and AdjustEndpoints responses larger than maxBytes,
+// nested deeper than maxDepth or holding more than maxElements JSON tokens, before decoding them.
+// This protects external-dns against a buggy or compromised webhook. A zero limit is not enforced.
+func WebhookWithDecodeLimits(maxBytes int64, maxDepth, maxElements int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.decodeLimits = &decodeLimits{maxBytes: maxBytes, maxDepth: maxDepth, maxElements: maxElements}
+	}
+}
+
+// decodeSnippetSize is the number of bytes shown on each side of the offset of a decode error
+const decodeSnippetSize = 64
+
+// DecodeError is returned when the body of a webhook response can't be decoded.
+// Offset and Snippet locate the malformed part of the body when the codec reports it.
+type DecodeError struct {
+	// Path is the path of the request URL
+	Path string
+	// Offset is the byte offset of the error in the body, zero when unknown
+	Offset int64
+	// Snippet is the part of the body surrounding Offset
+	Snippet string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Offset > 0 {
+		return fmt.Sprintf("failed to decode response of %s at offset %d near %q: %v", e.Path, e.Offset, e.Snippet, e.Err)
+	}
+	return fmt.Sprintf("failed to decode response of %s: %v", e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// newDecodeError locates err, returned when decoding body, in body
+func newDecodeError(path string, body []byte, err error) *DecodeError {
+	decodeErr := &DecodeError{Path: path, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		decodeErr.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		decodeErr.Offset = typeErr.Offset
+	}
+	if decodeErr.Offset > 0 && decodeErr.Offset <= int64(len(body)) {
+		start, end := decodeErr.Offset-decodeSnippetSize, decodeErr.Offset+decodeSnippetSize
+		if start < 0 {
+			start = 0
+		}
+		if end > int64(len(body)) {
+			end = int64(len(body))
+		}
+		decodeErr.Snippet = string(body[start:end])
+	}
+	return decodeErr
+}
+
+// WebhookWithDecodeRetry sends the requests of Records and AdjustEndpoints once more when their response
+// can't be decoded, e.g. because it was truncated by a gateway. A response failing to decode again fails the call.
+func WebhookWithDecodeRetry() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.decodeRetry = true
+	}
+}
+
+// retryOnDecodeError calls request, and calls it once more if it failed with a DecodeError and decode retries are enabled
+func (p WebhookProvider) retryOnDecodeError(path string, request func() error) error {
+	err := request()
+	var decodeErr *DecodeError
+	if !p.decodeRetry || !errors.As(err, &decodeErr) {
+		return err
+	}
+	log.Warnf("Failed to decode response of %s, retrying once: %v", path, err)
+	return request()
+}
+
+// decodeEndpoints decodes the endpoints of the response body with codec, after checking the decode limits
+func (p WebhookProvider) decodeEndpoints(codec Codec, resp *http.Response, endpoints *[]*endpoint.Endpoint) error {
+	b, err := p.readResponse(resp)
+	if err != nil {
+		return err
+	}
+	return decodeBody(codec, resp, b, endpoints)
+}
+
+// readResponse reads the decompressed body of resp, within the decode limits
+func (p WebhookProvider) readResponse(resp *http.Response) ([]byte, error) {
+	r, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	r, err = p.decodeLimits.check(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// decodeBody decodes the endpoints of the body of resp, returning a DecodeError on failure
+func decodeBody(codec Codec, resp *http.Response, b []byte, endpoints *[]*endpoint.Endpoint) error {
+	if err := codec.DecodeEndpoints(bytes.NewReader(b), endpoints); err != nil {
+		path := ""
+		if resp.Request != nil {
+			path = resp.Request.URL.Path
+		}
+		return newDecodeError(path, b, err)
+	}
+	return nil
+}
+
+// responseBody returns the decompressed body of resp. The transport only decompresses the responses
+// to the requests for which it negotiated gzip, so gateways compressing the response on their own
+// are handled here.
+func responseBody(resp *http.Response) (io.Reader, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get(contentEncodingHeader), "gzip") {
+		return resp.Body, nil
+	}
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response body: %w", err)
+	}
+	return r, nil
+}
+
+// check reads the whole document from r and returns a reader over it if it is within the limits
+func (l *decodeLimits) check(r io.Reader) (io.Reader, error) {
+	if l == nil {
+		return r, nil
+	}
+	if l.maxBytes > 0 {
+		r = io.LimitReader(r, l.maxBytes+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if l.maxBytes > 0 && int64(len(b)) > l.maxBytes {
+		return nil, fmt.Errorf("webhook response exceeds the maximum size of %d bytes", l.maxBytes)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	depth, elements := 0, 0
+	for {
+		t, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// malformed documents are reported by the codec
+			break
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if l.maxDepth > 0 && depth > l.maxDepth {
+				return nil, fmt.Errorf("webhook response exceeds the maximum nesting depth of %d", l.maxDepth)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+			continue
+		}
+		elements++
+		if l.maxElements > 0 && elements > l.maxElements {
+			return nil, fmt.Errorf("webhook response exceeds the maximum of %d elements", l.maxElements)
+		}
+	}
+	return bytes.NewReader(b), nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newPayloadServer(payload string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(payload))
+	}))
+}
+
+func TestDecodeLimits(t *testing.T) {
+	valid := `[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]}]`
+	for _, tc := range []struct {
+		name    string
+		payload string
+		err     string
+	}{
+		{
+			name:    "within limits",
+			payload: valid,
+		},
+		{
+			name:    "too deep",
+			payload: strings.Repeat("[", 20000) + strings.Repeat("]", 20000),
+			err:     "webhook response exceeds the maximum nesting depth of 10",
+		},
+		{
+			name:    "too many elements",
+			payload: "[" + strings.Repeat(`{},`, 500) + `{}]`,
+			err:     "webhook response exceeds the maximum of 100 elements",
+		},
+		{
+			name:    "too large",
+			payload: `[{"dnsName":"` + strings.Repeat("a", 70000) + `"}]`,
+			err:     "webhook response exceeds the maximum size of 65536 bytes",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := newPayloadServer(tc.payload)
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithDecodeLimits(64*1024, 10, 100))
+			require.NoError(t, err)
+
+			endpoints, err := provider.Records(context.TODO())
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, endpoints, 1)
+			}
+
+			_, err = provider.AdjustEndpoints([]*endpoint.Endpoint{})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChunkedGzipResponse(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`[{"dnsName":"a.example.com",`))
+		gz.Flush()
+		// flushing before the end of the body makes the server use chunked transfer encoding
+		w.(http.Flusher).Flush()
+		gz.Write([]byte(`"recordType":"A","targets":["1.2.3.4"]}]`))
+		gz.Close()
+	}))
+	defer svr.Close()
+
+	for _, disableCompression := range []bool{false, true} {
+		provider, err := NewWebhookProvider(svr.URL)
+		require.NoError(t, err)
+		// without transport compression, the gzip body is not decompressed by the transport
+		provider.transport.DisableCompression = disableCompression
+
+		endpoints, err := provider.Records(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, endpoints, 1)
+		require.Equal(t, "a.example.com", endpoints[0].DNSName)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	valid := `{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]},`
+	for _, tc := range []struct {
+		name    string
+		payload string
+		snippet string
+	}{
+		{
+			name:    "syntax error",
+			payload: "[" + strings.Repeat(valid, 100) + `{"dnsName":"bad.example.com","recordType":"A","targets":[invalid]}]`,
+			snippet: `"bad.example.com","recordType":"A","targets":[i`,
+		},
+		{
+			name:    "type error",
+			payload: "[" + strings.Repeat(valid, 100) + `{"dnsName":"bad.example.com","recordType":"A","recordTTL":"60"}]`,
+			snippet: `"bad.example.com","recordType":"A","recordTTL":"60"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := newPayloadServer(tc.payload)
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL)
+			require.NoError(t, err)
+
+			_, err = provider.Records(context.TODO())
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, "/records", decodeErr.Path)
+			require.Greater(t, decodeErr.Offset, int64(len(valid)*100))
+			require.Contains(t, decodeErr.Snippet, tc.snippet)
+			require.Contains(t, err.Error(), "failed to decode response of /records at offset")
+
+			_, err = provider.AdjustEndpoints([]*endpoint.Endpoint{})
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, "/adjustendpoints", decodeErr.Path)
+			require.Contains(t, decodeErr.Snippet, tc.snippet)
+		})
+	}
+
+	err := newDecodeError("/records", []byte(`[]`), io.ErrUnexpectedEOF)
+	require.EqualError(t, err, "failed to decode response of /records: unexpected EOF")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// newTruncatingServer returns a webhook server truncating its first truncated responses
+func newTruncatingServer(truncated int, calls *int) *httptest.Server {
+	payload := `[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]}]`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		*calls++
+		if *calls <= truncated {
+			w.Write([]byte(payload[:len(payload)/2]))
+			return
+		}
+		w.Write([]byte(payload))
+	}))
+}
+
+func TestDecodeRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		opts      []WebhookOption
+		truncated int
+		calls     int
+		err       bool
+	}{
+		{name: "disabled", truncated: 1, calls: 1, err: true},
+		{name: "truncated once", opts: []WebhookOption{WebhookWithDecodeRetry()}, truncated: 1, calls: 2},
+		{name: "truncated twice", opts: []WebhookOption{WebhookWithDecodeRetry()}, truncated: 2, calls: 2, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("records", func(t *testing.T) {
+				calls := 0
+				svr := newTruncatingServer(tc.truncated, &calls)
+				defer svr.Close()
+				provider, err := NewWebhookProvider(svr.URL, tc.opts...)
+				require.NoError(t, err)
+
+				endpoints, err := provider.Records(context.TODO())
+				require.Equal(t, tc.calls, calls)
+				if tc.err {
+					require.ErrorAs(t, err, new(*DecodeError))
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, endpoints, 1)
+			})
+			t.Run("adjustendpoints", func(t *testing.T) {
+				calls := 0
+				svr := newTruncatingServer(tc.truncated, &calls)
+				defer svr.Close()
+				provider, err := NewWebhookProvider(svr.URL, tc.opts...)
+				require.NoError(t, err)
+
+				endpoints, err := provider.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")})
+				require.Equal(t, tc.calls, calls)
+				if tc.err {
+					require.ErrorAs(t, err, new(*DecodeError))
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, endpoints, 1)
+			})
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithUpdateDiffLogging logs at debug level the fields that differ between
+// the old and new endpoint of every update sent by ApplyChanges
+func WebhookWithUpdateDiffLogging() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.logUpdateDiffs = true
+	}
+}
+
+func logUpdateDiffs(changes *plan.Changes) {
+	if changes == nil {
+		return
+	}
+	for i, updated := range changes.UpdateNew {
+		if i >= len(changes.UpdateOld) {
+			break
+		}
+		diff := diffEndpoints(changes.UpdateOld[i], updated)
+		if len(diff) == 0 {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"dnsName":    updated.DNSName,
+			"recordType": updated.RecordType,
+		}).Debugf("Updating endpoint: %s", strings.Join(diff, ", "))
+	}
+}
+
+// diffEndpoints returns a description of every field that differs between current and desired
+func diffEndpoints(current, desired *endpoint.Endpoint) []string {
+	var diff []string
+	if current.DNSName != desired.DNSName {
+		diff = append(diff, fmt.Sprintf("dnsName %s -> %s", current.DNSName, desired.DNSName))
+	}
+	if current.RecordType != desired.RecordType {
+		diff = append(diff, fmt.Sprintf("recordType %s -> %s", current.RecordType, desired.RecordType))
+	}
+	if current.SetIdentifier != desired.SetIdentifier {
+		diff = append(diff, fmt.Sprintf("setIdentifier %q -> %q", current.SetIdentifier, desired.SetIdentifier))
+	}
+	if current.RecordTTL != desired.RecordTTL {
+		diff = append(diff, fmt.Sprintf("ttl %d -> %d", current.RecordTTL, desired.RecordTTL))
+	}
+	added, removed := diffTargets(current.Targets, desired.Targets)
+	if len(added) > 0 {
+		diff = append(diff, fmt.Sprintf("targets added %v", added))
+	}
+	if len(removed) > 0 {
+		diff = append(diff, fmt.Sprintf("targets removed %v", removed))
+	}
+	diff = append(diff, diffProperties("providerSpecific", providerSpecificMap(current.ProviderSpecific), providerSpecificMap(desired.ProviderSpecific))...)
+	diff = append(diff, diffProperties("label", current.Labels, desired.Labels)...)
+	return diff
+}
+
+func diffTargets(current, desired endpoint.Targets) (added, removed []string) {
+	currentSet := map[string]bool{}
+	for _, t := range current {
+		currentSet[t] = true
+	}
+	desiredSet := map[string]bool{}
+	for _, t := range desired {
+		desiredSet[t] = true
+		if !currentSet[t] {
+			added = append(added, t)
+		}
+	}
+	for _, t := range current {
+		if !desiredSet[t] {
+			removed = append(removed, t)
+		}
+	}
+	return added, removed
+}
+
+func providerSpecificMap(ps endpoint.ProviderSpecific) map[string]string {
+	m := make(map[string]string, len(ps))
+	for _, p := range ps {
+		m[p.Name] = p.Value
+	}
+	return m
+}
+
+func diffProperties(kind string, current, desired map[string]string) []string {
+	names := map[string]bool{}
+	for name := range current {
+		names[name] = true
+	}
+	for name := range desired {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diff []string
+	for _, name := range sorted {
+		currentValue, inCurrent := current[name]
+		desiredValue, inDesired := desired[name]
+		switch {
+		case !inCurrent:
+			diff = append(diff, fmt.Sprintf("%s %s added %q", kind, name, desiredValue))
+		case !inDesired:
+			diff = append(diff, fmt.Sprintf("%s %s removed", kind, name))
+		case currentValue != desiredValue:
+			diff = append(diff, fmt.Sprintf("%s %s %q -> %q", kind, name, currentValue, desiredValue))
+		}
+	}
+	return diff
+}
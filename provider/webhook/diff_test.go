@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestDiffEndpoints(t *testing.T) {
+	base := func() *endpoint.Endpoint {
+		return &endpoint.Endpoint{
+			DNSName:          "a.example.com",
+			RecordType:       endpoint.RecordTypeA,
+			RecordTTL:        300,
+			Targets:          endpoint.Targets{"1.1.1.1", "2.2.2.2"},
+			ProviderSpecific: endpoint.ProviderSpecific{{Name: "weight", Value: "10"}},
+			Labels:           endpoint.Labels{"owner": "default"},
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		modify func(e *endpoint.Endpoint)
+		diff   []string
+	}{
+		{
+			name:   "identical",
+			modify: func(e *endpoint.Endpoint) {},
+		},
+		{
+			name:   "dns name",
+			modify: func(e *endpoint.Endpoint) { e.DNSName = "b.example.com" },
+			diff:   []string{"dnsName a.example.com -> b.example.com"},
+		},
+		{
+			name:   "record type",
+			modify: func(e *endpoint.Endpoint) { e.RecordType = endpoint.RecordTypeAAAA },
+			diff:   []string{"recordType A -> AAAA"},
+		},
+		{
+			name:   "set identifier",
+			modify: func(e *endpoint.Endpoint) { e.SetIdentifier = "eu" },
+			diff:   []string{`setIdentifier "" -> "eu"`},
+		},
+		{
+			name:   "ttl",
+			modify: func(e *endpoint.Endpoint) { e.RecordTTL = 60 },
+			diff:   []string{"ttl 300 -> 60"},
+		},
+		{
+			name:   "targets",
+			modify: func(e *endpoint.Endpoint) { e.Targets = endpoint.Targets{"2.2.2.2", "3.3.3.3"} },
+			diff:   []string{"targets added [3.3.3.3]", "targets removed [1.1.1.1]"},
+		},
+		{
+			name: "provider specific",
+			modify: func(e *endpoint.Endpoint) {
+				e.ProviderSpecific = endpoint.ProviderSpecific{{Name: "weight", Value: "20"}, {Name: "region", Value: "eu"}}
+			},
+			diff: []string{`providerSpecific region added "eu"`, `providerSpecific weight "10" -> "20"`},
+		},
+		{
+			name:   "labels",
+			modify: func(e *endpoint.Endpoint) { e.Labels = endpoint.Labels{} },
+			diff:   []string{"label owner removed"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			desired := base()
+			tc.modify(desired)
+			require.Equal(t, tc.diff, diffEndpoints(base(), desired))
+		})
+	}
+}
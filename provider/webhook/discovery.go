@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// srvResolver is the subset of net.Resolver used to discover the webhook
+type srvResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// srvDialer dials the targets of an SRV record instead of the host of the webhook URL.
+// The record is resolved again on every new connection so that failed backends are replaced
+// as soon as the record is updated.
+type srvDialer struct {
+	name     string
+	resolver srvResolver
+	dialer   *net.Dialer
+}
+
+// DialContext resolves the SRV record and connects to the first reachable target,
+// in the order of priority and weight returned by the resolver.
+func (d *srvDialer) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	_, addrs, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SRV record %s: %w", d.name, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no targets found for SRV record %s", d.name)
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		target := net.JoinHostPort(strings.TrimSuffix(addr.Target, "."), strconv.Itoa(int(addr.Port)))
+		conn, err := d.dialer.DialContext(ctx, network, target)
+		if err == nil {
+			return conn, nil
+		}
+		log.Debugf("Failed to connect to webhook target %s from SRV record %s: %v", target, d.name, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to connect to any target of SRV record %s: %w", d.name, lastErr)
+}
+
+// WebhookWithSRVDiscovery resolves the webhook through the given SRV record,
+// e.g. _externaldns-webhook._tcp.ns.svc.cluster.local, instead of the host of the webhook URL.
+// The scheme and path of the webhook URL are still used. When name is empty, the static URL is used.
+func WebhookWithSRVDiscovery(name string) WebhookOption {
+	return func(p *WebhookProvider) {
+		if name == "" {
+			return
+		}
+		d := &srvDialer{
+			name:     name,
+			resolver: net.DefaultResolver,
+			dialer:   p.dialer,
+		}
+		p.transport.DialContext = d.DialContext
+	}
+}
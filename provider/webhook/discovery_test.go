@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSRVResolver struct {
+	addrs   []*net.SRV
+	lookups int
+}
+
+func (r *fakeSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.lookups++
+	if name != "_externaldns-webhook._tcp.ns.svc" {
+		return "", nil, fmt.Errorf("no such host %s", name)
+	}
+	return "", r.addrs, nil
+}
+
+func srvTarget(t *testing.T, rawURL string) *net.SRV {
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return &net.SRV{Target: u.Hostname() + ".", Port: uint16(port)}
+}
+
+// newSRVProvider creates a provider discovering the webhook with the given resolver
+func newSRVProvider(resolver srvResolver) (*WebhookProvider, error) {
+	return NewWebhookProvider("http://webhook.invalid", func(p *WebhookProvider) {
+		p.transport.DialContext = (&srvDialer{
+			name:     "_externaldns-webhook._tcp.ns.svc",
+			resolver: resolver,
+			dialer:   &net.Dialer{},
+		}).DialContext
+	})
+}
+
+func TestSRVDiscovery(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[{"dnsName": "test.example.com"}]`))
+	}))
+	defer svr.Close()
+
+	resolver := &fakeSRVResolver{addrs: []*net.SRV{srvTarget(t, svr.URL)}}
+	provider, err := newSRVProvider(resolver)
+	require.NoError(t, err)
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.GreaterOrEqual(t, resolver.lookups, 1)
+}
+
+func TestSRVDiscoveryFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downTarget := srvTarget(t, down.URL)
+	down.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer up.Close()
+
+	resolver := &fakeSRVResolver{addrs: []*net.SRV{downTarget, srvTarget(t, up.URL)}}
+	provider, err := newSRVProvider(resolver)
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+}
+
+func TestSRVDiscoveryNoTargets(t *testing.T) {
+	d := &srvDialer{
+		name:     "_externaldns-webhook._tcp.ns.svc",
+		resolver: &fakeSRVResolver{},
+		dialer:   &net.Dialer{},
+	}
+	_, err := d.DialContext(context.TODO(), "tcp", "webhook.invalid:80")
+	require.EqualError(t, err, "no targets found for SRV record _externaldns-webhook._tcp.ns.svc")
+}
+
+func TestSRVDiscoveryDisabled(t *testing.T) {
+	p := &WebhookProvider{transport: &http.Transport{}}
+	WebhookWithSRVDiscovery("")(p)
+	require.Nil(t, p.transport.DialContext)
+}
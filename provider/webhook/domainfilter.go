@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"errors"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// WebhookWithRegexDomainFilter replaces the domain filter negotiated with the webhook by a filter matching
+// the DNS names matching include and not matching exclude, either of which can be nil.
+// Webhooks can also advertise such a filter themselves, with regexInclude and regexExclude in the response to /.
+func WebhookWithRegexDomainFilter(include, exclude *regexp.Regexp) WebhookOption {
+	return func(p *WebhookProvider) {
+		df := endpoint.NewRegexDomainFilter(include, exclude)
+		p.regexDomainFilter = &df
+	}
+}
+
+// negotiatedDomainFilter returns the domain filter to use given the one negotiated with the webhook
+func (p WebhookProvider) negotiatedDomainFilter(negotiated endpoint.DomainFilter) endpoint.DomainFilter {
+	if p.regexDomainFilter == nil {
+		return negotiated
+	}
+	if negotiated.IsConfigured() {
+		log.Infof("Using the configured regex domain filter instead of the domain filter of the webhook")
+	}
+	return *p.regexDomainFilter
+}
+
+// validateZoneDomainFilter rejects the options finding the zones of the endpoints in the domains of the domain filter
+// when it is a regex filter, which has no domains: they would otherwise treat all the endpoints as outside of any zone
+func (p WebhookProvider) validateZoneDomainFilter(regexFilter bool) error {
+	if !regexFilter {
+		return nil
+	}
+	switch {
+	case p.zoneConcurrency > 0:
+		return errors.New("zone concurrency requires the zones of the domain filter, which is a regex filter")
+	case p.zoneScopedRecords && len(p.recordZones) == 0:
+		return errors.New("zone scoped records require explicit zones when the domain filter is a regex filter")
+	case p.apexCNAME != "":
+		return errors.New("apex CNAME handling requires the zones of the domain filter, which is a regex filter")
+	}
+	return nil
+}
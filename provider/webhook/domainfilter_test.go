@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func newDomainFilterServer(filter string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.Write([]byte(filter))
+	}))
+}
+
+func TestRegexDomainFilter(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filter   string
+		opts     []WebhookOption
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "negotiated include",
+			filter:   `{"regexInclude": "^.*\\.staging\\.example\\.com$"}`,
+			included: []string{"a.staging.example.com", "b.a.staging.example.com"},
+			excluded: []string{"staging.example.com", "a.example.com", "a.staging.example.org"},
+		},
+		{
+			name:     "negotiated exclude",
+			filter:   `{"regexExclude": "^internal\\."}`,
+			included: []string{"a.example.com"},
+			excluded: []string{"internal.example.com"},
+		},
+		{
+			name:     "configured",
+			filter:   `{"include": ["example.org"]}`,
+			opts:     []WebhookOption{WebhookWithRegexDomainFilter(regexp.MustCompile(`^.*\.staging\.example\.com$`), nil)},
+			included: []string{"a.staging.example.com"},
+			excluded: []string{"a.example.org", "a.example.com"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := newDomainFilterServer(tc.filter)
+			defer svr.Close()
+
+			p, err := NewWebhookProvider(svr.URL, tc.opts...)
+			require.NoError(t, err)
+			df := p.GetDomainFilter()
+			for _, name := range tc.included {
+				require.True(t, df.Match(name), name)
+			}
+			for _, name := range tc.excluded {
+				require.False(t, df.Match(name), name)
+			}
+
+			// the filter survives the serialization, e.g. by the HTTP API of a webhook server
+			b, err := json.Marshal(df)
+			require.NoError(t, err)
+			var served endpoint.DomainFilter
+			require.NoError(t, json.Unmarshal(b, &served))
+			for _, name := range tc.included {
+				require.True(t, served.Match(name), name)
+			}
+		})
+	}
+}
+
+func TestRegexDomainFilterInvalid(t *testing.T) {
+	svr := newDomainFilterServer(`{"regexInclude": "("}`)
+	defer svr.Close()
+
+	_, err := NewWebhookProvider(svr.URL)
+	require.ErrorContains(t, err, "invalid regexInclude")
+}
+
+func TestRegexDomainFilterZones(t *testing.T) {
+	svr := newDomainFilterServer(`{"include":["example.com"]}`)
+	defer svr.Close()
+	regexSvr := newDomainFilterServer(`{"regexInclude":"example\\.com$"}`)
+	defer regexSvr.Close()
+	regexFilter := WebhookWithRegexDomainFilter(regexp.MustCompile(`example\.com$`), nil)
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		opts []WebhookOption
+		err  string
+	}{
+		{name: "zone concurrency", url: svr.URL, opts: []WebhookOption{WebhookWithZoneConcurrency(2)}},
+		{name: "configured regex zone concurrency", url: svr.URL, opts: []WebhookOption{regexFilter, WebhookWithZoneConcurrency(2)}, err: "zone concurrency requires the zones of the domain filter, which is a regex filter"},
+		{name: "advertised regex zone concurrency", url: regexSvr.URL, opts: []WebhookOption{WebhookWithZoneConcurrency(2)}, err: "zone concurrency requires the zones of the domain filter, which is a regex filter"},
+		{name: "regex zone scoped records", url: regexSvr.URL, opts: []WebhookOption{WebhookWithZoneScopedRecords()}, err: "zone scoped records require explicit zones when the domain filter is a regex filter"},
+		{name: "regex explicit zone scoped records", url: regexSvr.URL, opts: []WebhookOption{WebhookWithZoneScopedRecords("example.com")}},
+		{name: "regex apex CNAME", url: regexSvr.URL, opts: []WebhookOption{WebhookWithApexCNAME(ApexCNAMEReject)}, err: "apex CNAME handling requires the zones of the domain filter, which is a regex filter"},
+		{name: "regex without zones", url: regexSvr.URL},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewWebhookProvider(tc.url, tc.opts...)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
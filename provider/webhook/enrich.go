@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Enricher adds metadata from an external source, e.g. an IPAM service, to the endpoints sent to the webhook
+type Enricher interface {
+	Enrich(ctx context.Context, e *endpoint.Endpoint) error
+}
+
+// WebhookWithEnricher enriches the endpoints created or updated by ApplyChanges with enricher.
+// Failing to enrich an endpoint is logged and the endpoint is sent unchanged.
+func WebhookWithEnricher(enricher Enricher) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.enrichers = append(p.enrichers, enricher)
+	}
+}
+
+// enrichChanges enriches the endpoints created or updated by changes, which must be a copy of the plan
+func (p WebhookProvider) enrichChanges(ctx context.Context, changes *plan.Changes) {
+	if changes == nil {
+		return
+	}
+	for _, enricher := range p.enrichers {
+		for _, endpoints := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew} {
+			for _, e := range endpoints {
+				if err := enricher.Enrich(ctx, e); err != nil {
+					log.Warnf("Failed to enrich endpoint %s: %v", e.DNSName, err)
+				}
+			}
+		}
+	}
+}
+
+// HTTPEnricher looks up the provider specific properties of an endpoint with a GET request to
+// a metadata service, passing the DNS name in the dnsName query parameter. The service responds
+// with a JSON object mapping property names to their values, or 404 if it has none.
+type HTTPEnricher struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPEnricher returns a HTTPEnricher querying url, using http.DefaultClient if client is nil
+func NewHTTPEnricher(url string, client *http.Client) *HTTPEnricher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPEnricher{client: client, url: url}
+}
+
+// Enrich sets the provider specific properties returned by the metadata service on e
+func (h *HTTPEnricher) Enrich(ctx context.Context, e *endpoint.Endpoint) error {
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("dnsName", e.DNSName)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("metadata lookup failed with code %d", resp.StatusCode)
+	}
+
+	properties := map[string]string{}
+	if err := json.NewDecoder(resp.Body).Decode(&properties); err != nil {
+		return fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.SetProviderSpecificProperty(name, properties[name])
+	}
+	return nil
+}
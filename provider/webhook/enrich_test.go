@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestHTTPEnricher(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("dnsName") {
+		case "a.example.com":
+			w.Write([]byte(`{"ipam/owner":"team-a","ipam/network":"10.0.0.0/8"}`))
+		case "b.example.com":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer metadata.Close()
+
+	var applied plan.Changes
+	svr := newApplyServer(t, &applied)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithEnricher(NewHTTPEnricher(metadata.URL, nil)))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}},
+			{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.2"}},
+		},
+		UpdateOld: []*endpoint.Endpoint{{DNSName: "c.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.3"}}},
+		UpdateNew: []*endpoint.Endpoint{{DNSName: "c.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.4"}}},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	require.Equal(t, endpoint.ProviderSpecific{
+		{Name: "ipam/network", Value: "10.0.0.0/8"},
+		{Name: "ipam/owner", Value: "team-a"},
+	}, applied.Create[0].ProviderSpecific)
+	// a failed lookup leaves the endpoint unchanged
+	require.Empty(t, applied.Create[1].ProviderSpecific)
+	require.Empty(t, applied.UpdateNew[0].ProviderSpecific)
+	// the plan is left untouched
+	require.Empty(t, changes.Create[0].ProviderSpecific)
+}
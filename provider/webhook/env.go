@@ -0,0 +1,402 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// EnvConfig is the webhook provider configuration read from the environment by LoadEnvConfig
+type EnvConfig struct {
+	// URL is the URL of the webhook, empty if not set
+	URL string
+	// Routes are the webhooks of the domains, empty if not set
+	Routes  []WebhookRoute
+	Options []WebhookOption
+}
+
+// LoadEnvConfig reads the webhook provider configuration from the environment variables with the given prefix,
+// e.g. EXTERNAL_DNS_WEBHOOK_URL for the prefix EXTERNAL_DNS_WEBHOOK_:
+//
+//   - URL: URL of the webhook
+//   - ROUTES: comma separated webhooks of the domains, e.g. a.example.com=http://a:8888, see WebhookRouter
+//   - READ_ONLY: only log the changes, see WebhookWithReadOnly
+//   - RETRIES: number of retries of failed requests
+//   - RETRY_OPERATIONS: comma separated calls retried, records and adjustendpoints by default, see WebhookWithRetriedOperations
+//   - STATUS_CLASSES: comma separated handling of the status codes, e.g. 422=fatal,409=skip, see WebhookWithStatusClasses
+//   - NEGOTIATION_TIMEOUT: timeout of the negotiation with the webhook at startup, e.g. 10s
+//   - ADJUST_ENDPOINTS_TIMEOUT: timeout of AdjustEndpoints, e.g. 5s
+//   - TCP_KEEPALIVE: interval of the TCP keep-alive probes, e.g. 30s
+//   - DIAL_TIMEOUT: timeout of the connection to the webhook, e.g. 5s
+//   - REQUEST_TIMEOUT: timeout of each request to the webhook, e.g. 1m
+//   - MAX_ENDPOINTS: maximum number of endpoints changed per reconcile
+//   - BATCH_SIZE, BATCH_BYTES: maximum number of endpoints and of bytes of each request applying changes
+//   - DEFAULT_TTL: TTL of the endpoints without one, in seconds
+//   - ZERO_TTL: what is sent for the zero TTLs, omit, default or zero, see WebhookWithZeroTTL
+//   - DEDUPLICATE_TARGETS: remove the duplicate targets of the endpoints, see WebhookWithDeduplicatedTargets
+//   - APPLY_ORDER: comma separated order of the operations, e.g. create,update,delete, the missing ones being sent last
+//   - APPLY_METHOD: POST or PUT
+//   - PAYLOAD_ORDER: comma separated order of the operations within a request, see WebhookWithPayloadOrder
+//   - PREVIEW: send the changes to /records/preview before applying them, see WebhookWithPreview
+//   - TRANSACTIONS: apply the changes within a transaction, see WebhookWithTransactions
+//   - ERROR_ISOLATION: retry the changes without the endpoints rejected by the webhook, see WebhookWithErrorIsolation
+//   - REQUEST_COMPRESSION: gzip the bodies applying changes larger than the given number of bytes
+//   - LABEL_KEY_PATTERN, LABEL_VALUE_PATTERN: regular expressions the label keys and values must match
+//   - REGEX_DOMAIN_FILTER, REGEX_DOMAIN_EXCLUSION: regular expressions of the DNS names included and excluded, see WebhookWithRegexDomainFilter
+//   - PROTECTED_RECORDS: comma separated glob patterns of the records never deleted
+//   - DELETE_GRACE_PERIOD: duration the deletions are deferred, e.g. 10m, see WebhookWithDeleteGracePeriod
+//   - TOMBSTONES: soft-delete the records, see WebhookWithTombstones
+//   - PROVIDER_SPECIFIC_ALLOWLIST: comma separated provider specific properties sent to the webhook, the others being removed
+//   - ERROR_LOG_THROTTLE: window in which an identical error is logged once, e.g. 5m, see WebhookWithErrorLogThrottle
+//   - JSON_PROPERTIES, CASE_INSENSITIVE_PROPERTIES: comma separated provider specific properties compared as JSON or ignoring case
+//   - AUDIT_ID_HEADER: header carrying the Kubernetes audit ID of the reconcile, see WebhookWithAuditIDHeader
+//   - PLAN_REVIEW_FILE: file the changes are appended to before being applied, see WebhookWithPlanReview
+//   - ENVELOPE_CHANGES_FIELD, ENVELOPE_METADATA_FIELD, ENVELOPE_CONTROLLER_ID, ENVELOPE_RECORDS_FIELD:
+//     envelope wrapping the payloads, see WebhookWithEnvelope
+//   - TOKEN_FILE: file containing the bearer token
+//   - HMAC_SECRET_FILE: file containing the secret of the HMAC-SHA256 signature of the requests, see WebhookWithHMACSignature
+//   - OAUTH2_CLIENT_ID, OAUTH2_CLIENT_SECRET, OAUTH2_TOKEN_URL, OAUTH2_SCOPES: OAuth2 client credentials
+//   - CA_FILE, CERT_FILE, KEY_FILE, TLS_SERVER_NAME, TLS_INSECURE: TLS configuration, the client certificate
+//     being reloaded when its files change
+//   - TLS_SPKI_PINS: comma separated base64 SHA-256 digests of the accepted public keys of the webhook
+//
+// The other options, e.g. the ones taking a callback or an interface such as WebhookWithEnricher or WebhookWithSinks,
+// can only be set in code.
+// Malformed values are reported with the name of the variable.
+func LoadEnvConfig(prefix string) (*EnvConfig, error) {
+	l := envLoader{prefix: prefix}
+	cfg := &EnvConfig{URL: l.string("URL")}
+	if v := l.string("ROUTES"); v != "" {
+		routes, err := ParseWebhookRoutes(v)
+		l.fail("ROUTES", v, err)
+		cfg.Routes = routes
+	}
+
+	if l.boolean("READ_ONLY") {
+		cfg.Options = append(cfg.Options, WebhookWithReadOnly())
+	}
+	if retries, ok := l.integer("RETRIES"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithRetries(retries))
+	}
+	if v := l.string("RETRY_OPERATIONS"); v != "" {
+		ops, err := ParseRetryOperations(v)
+		l.fail("RETRY_OPERATIONS", v, err)
+		cfg.Options = append(cfg.Options, WebhookWithRetriedOperations(ops...))
+	}
+	if v := l.string("STATUS_CLASSES"); v != "" {
+		classes, err := ParseStatusClasses(v)
+		l.fail("STATUS_CLASSES", v, err)
+		cfg.Options = append(cfg.Options, WebhookWithStatusClasses(classes))
+	}
+	if timeout, ok := l.duration("NEGOTIATION_TIMEOUT"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithNegotiationTimeout(timeout))
+	}
+	if timeout, ok := l.duration("ADJUST_ENDPOINTS_TIMEOUT"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithAdjustEndpointsTimeout(timeout))
+	}
+	if interval, ok := l.duration("TCP_KEEPALIVE"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithTCPKeepAlive(interval))
+	}
+	if timeout, ok := l.duration("DIAL_TIMEOUT"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithDialTimeout(timeout))
+	}
+	if timeout, ok := l.duration("REQUEST_TIMEOUT"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithRequestTimeout(timeout))
+	}
+	if max, ok := l.integer("MAX_ENDPOINTS"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithMaxEndpoints(max))
+	}
+	if size, ok := l.integer("BATCH_SIZE"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithBatchSize(size))
+	}
+	if size, ok := l.integer("BATCH_BYTES"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithBatchBytes(size))
+	}
+	if ttl, ok := l.integer("DEFAULT_TTL"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithDefaultTTL(endpoint.TTL(ttl)))
+	}
+	if l.boolean("DEDUPLICATE_TARGETS") {
+		cfg.Options = append(cfg.Options, WebhookWithDeduplicatedTargets())
+	}
+	if v := l.string("ZERO_TTL"); v != "" {
+		mode, err := ParseZeroTTLMode(v)
+		l.fail("ZERO_TTL", v, err)
+		cfg.Options = append(cfg.Options, WebhookWithZeroTTL(mode))
+	}
+	if order := l.string("APPLY_ORDER"); order != "" {
+		ops, err := ParseApplyOrder(order)
+		l.fail("APPLY_ORDER", order, err)
+		cfg.Options = append(cfg.Options, WebhookWithApplyOrder(ops...))
+	}
+	if method := l.string("APPLY_METHOD"); method != "" {
+		cfg.Options = append(cfg.Options, WebhookWithApplyMethod(method))
+	}
+	if order := l.string("PAYLOAD_ORDER"); order != "" {
+		ops, err := ParseApplyOrder(order)
+		l.fail("PAYLOAD_ORDER", order, err)
+		cfg.Options = append(cfg.Options, WebhookWithPayloadOrder(ops...))
+	}
+	if l.boolean("PREVIEW") {
+		cfg.Options = append(cfg.Options, WebhookWithPreview())
+	}
+	if l.boolean("TRANSACTIONS") {
+		cfg.Options = append(cfg.Options, WebhookWithTransactions())
+	}
+	if l.boolean("ERROR_ISOLATION") {
+		cfg.Options = append(cfg.Options, WebhookWithErrorIsolation())
+	}
+	if minBytes, ok := l.integer("REQUEST_COMPRESSION"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithRequestCompression(minBytes))
+	}
+	if patterns := l.list("PROTECTED_RECORDS"); len(patterns) > 0 {
+		cfg.Options = append(cfg.Options, WebhookWithProtectedRecords(patterns...))
+	}
+	if l.boolean("TOMBSTONES") {
+		cfg.Options = append(cfg.Options, WebhookWithTombstones())
+	}
+	envelope := Envelope{
+		ChangesField:  l.string("ENVELOPE_CHANGES_FIELD"),
+		MetadataField: l.string("ENVELOPE_METADATA_FIELD"),
+		ControllerID:  l.string("ENVELOPE_CONTROLLER_ID"),
+		RecordsField:  l.string("ENVELOPE_RECORDS_FIELD"),
+	}
+	if envelope != (Envelope{}) {
+		cfg.Options = append(cfg.Options, WebhookWithEnvelope(envelope))
+	}
+	if keys := l.list("PROVIDER_SPECIFIC_ALLOWLIST"); len(keys) > 0 {
+		cfg.Options = append(cfg.Options, WebhookWithProviderSpecificAllowlist(keys...))
+	}
+	if window, ok := l.duration("ERROR_LOG_THROTTLE"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithErrorLogThrottle(window))
+	}
+	for _, name := range l.list("JSON_PROPERTIES") {
+		cfg.Options = append(cfg.Options, WebhookWithPropertyComparator(name, plan.JSONPropertyComparator))
+	}
+	for _, name := range l.list("CASE_INSENSITIVE_PROPERTIES") {
+		cfg.Options = append(cfg.Options, WebhookWithPropertyComparator(name, plan.CaseInsensitivePropertyComparator))
+	}
+	if header := l.string("AUDIT_ID_HEADER"); header != "" {
+		cfg.Options = append(cfg.Options, WebhookWithAuditIDHeader(header))
+	}
+	if key, value := l.regexp("LABEL_KEY_PATTERN"), l.regexp("LABEL_VALUE_PATTERN"); key != nil || value != nil {
+		cfg.Options = append(cfg.Options, WebhookWithLabelValidation(key, value))
+	}
+	if include, exclude := l.regexp("REGEX_DOMAIN_FILTER"), l.regexp("REGEX_DOMAIN_EXCLUSION"); include != nil || exclude != nil {
+		cfg.Options = append(cfg.Options, WebhookWithRegexDomainFilter(include, exclude))
+	}
+	if path := l.string("PLAN_REVIEW_FILE"); path != "" {
+		// the file is opened again for every review, but an unusable path fails early
+		f, err := openPlanReviewFile(path)
+		if err == nil {
+			err = f.Close()
+		}
+		l.fail("PLAN_REVIEW_FILE", path, err)
+		cfg.Options = append(cfg.Options, WebhookWithPlanReviewFile(path))
+	}
+	if grace, ok := l.duration("DELETE_GRACE_PERIOD"); ok {
+		cfg.Options = append(cfg.Options, WebhookWithDeleteGracePeriod(grace))
+	}
+
+	if path := l.string("TOKEN_FILE"); path != "" {
+		a, err := NewTokenFileAuthenticator(path)
+		l.fail("TOKEN_FILE", path, err)
+		cfg.Options = append(cfg.Options, WebhookWithAuthenticator(a))
+	}
+	if path := l.string("HMAC_SECRET_FILE"); path != "" {
+		secret, err := os.ReadFile(path)
+		l.fail("HMAC_SECRET_FILE", path, err)
+		cfg.Options = append(cfg.Options, WebhookWithHMACSignature(bytes.TrimSpace(secret), sha256.New))
+	}
+	clientID, clientSecret, tokenURL := l.string("OAUTH2_CLIENT_ID"), l.string("OAUTH2_CLIENT_SECRET"), l.string("OAUTH2_TOKEN_URL")
+	if clientID != "" || clientSecret != "" || tokenURL != "" {
+		if clientID == "" || clientSecret == "" || tokenURL == "" {
+			l.setErr(fmt.Errorf("%[1]sOAUTH2_CLIENT_ID, %[1]sOAUTH2_CLIENT_SECRET and %[1]sOAUTH2_TOKEN_URL must be set together", prefix))
+		}
+		cfg.Options = append(cfg.Options, WebhookWithOAuth2ClientCredentials(clientID, clientSecret, tokenURL, l.list("OAUTH2_SCOPES")))
+	}
+
+	for _, name := range []string{"CA_FILE", "CERT_FILE", "KEY_FILE", "TLS_SERVER_NAME", "TLS_INSECURE"} {
+		if l.string(name) == "" {
+			continue
+		}
+		tlsConfig, err := tlsutils.CreateTLSConfig(strings.TrimSuffix(prefix, "_"))
+		if err != nil {
+			l.setErr(fmt.Errorf("invalid TLS configuration: %w", err))
+		}
+		cfg.Options = append(cfg.Options, WebhookWithTLSConfig(tlsConfig))
+		if certFile, keyFile := l.string("CERT_FILE"), l.string("KEY_FILE"); err == nil && certFile != "" {
+			// reload the rotated certificates without restarting
+			reloader, err := NewCertificateReloader(certFile, keyFile)
+			if err != nil {
+				l.setErr(fmt.Errorf("invalid TLS configuration: %w", err))
+			}
+			cfg.Options = append(cfg.Options, WebhookWithClientCertificate(reloader))
+		}
+		break
+	}
+	if values := l.list("TLS_SPKI_PINS"); len(values) > 0 {
+		pins := make([]SPKIPin, 0, len(values))
+		for _, v := range values {
+			pin, err := ParseSPKIPin(v)
+			l.fail("TLS_SPKI_PINS", v, err)
+			pins = append(pins, pin)
+		}
+		cfg.Options = append(cfg.Options, WebhookWithSPKIPins(pins...))
+	}
+
+	if l.err != nil {
+		return nil, l.err
+	}
+	return cfg, nil
+}
+
+// NewWebhookProviderFromEnv creates a webhook provider configured by LoadEnvConfig with the given prefix,
+// using defaultURL when the URL is not set in the environment
+func NewWebhookProviderFromEnv(prefix, defaultURL string, opts ...WebhookOption) (*WebhookProvider, error) {
+	cfg, err := LoadEnvConfig(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return newWebhookProviderFromConfig(cfg, defaultURL, opts...)
+}
+
+// newWebhookProviderFromConfig creates a webhook provider configured by cfg, using defaultURL when cfg has no URL
+func newWebhookProviderFromConfig(cfg *EnvConfig, defaultURL string, opts ...WebhookOption) (*WebhookProvider, error) {
+	u := defaultURL
+	if cfg.URL != "" {
+		u = cfg.URL
+	}
+	return NewWebhookProvider(u, append(cfg.Options, opts...)...)
+}
+
+// NewProviderFromEnv returns a WebhookRouter when routes are configured by the environment,
+// and otherwise the webhook provider returned by NewWebhookProviderFromEnv
+func NewProviderFromEnv(prefix, defaultURL string, opts ...WebhookOption) (provider.Provider, error) {
+	cfg, err := LoadEnvConfig(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Routes) == 0 {
+		p, err := newWebhookProviderFromConfig(cfg, defaultURL, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	if cfg.URL != "" {
+		return nil, fmt.Errorf("%[1]sURL and %[1]sROUTES are mutually exclusive", prefix)
+	}
+	r, err := NewWebhookRouter(cfg.Routes, append(cfg.Options, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// envLoader reads environment variables with a prefix, keeping the first malformed value as error
+type envLoader struct {
+	prefix string
+	err    error
+}
+
+func (l *envLoader) string(name string) string {
+	return strings.TrimSpace(os.Getenv(l.prefix + name))
+}
+
+func (l *envLoader) list(name string) []string {
+	var values []string
+	for _, v := range strings.Split(l.string(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (l *envLoader) boolean(name string) bool {
+	v := l.string(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	l.fail(name, v, err)
+	return b
+}
+
+func (l *envLoader) integer(name string) (int, bool) {
+	v := l.string(name)
+	if v == "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	if err == nil && i < 0 {
+		err = fmt.Errorf("must not be negative")
+	}
+	l.fail(name, v, err)
+	return i, err == nil
+}
+
+func (l *envLoader) duration(name string) (time.Duration, bool) {
+	v := l.string(name)
+	if v == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	l.fail(name, v, err)
+	return d, err == nil
+}
+
+// regexp compiles the regular expression of the variable name, nil if not set
+func (l *envLoader) regexp(name string) *regexp.Regexp {
+	v := l.string(name)
+	if v == "" {
+		return nil
+	}
+	re, err := regexp.Compile(v)
+	l.fail(name, v, err)
+	return re
+}
+
+// fail records err as the error of the value v of the variable name
+func (l *envLoader) fail(name, v string, err error) {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	if err != nil {
+		l.setErr(fmt.Errorf("invalid value %q for %s%s: %w", v, l.prefix, name, err))
+	}
+}
+
+func (l *envLoader) setErr(err error) {
+	if l.err == nil {
+		l.err = err
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const testEnvPrefix = "TEST_WEBHOOK_"
+
+func TestLoadEnvConfig(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret"), 0o600))
+	secretFile := filepath.Join(t.TempDir(), "hmac")
+	require.NoError(t, os.WriteFile(secretFile, []byte("hmac-secret\n"), 0o600))
+	reviewFile := filepath.Join(t.TempDir(), "review.json")
+
+	for name, value := range map[string]string{
+		"URL":                         "http://localhost:9999",
+		"ROUTES":                      "a.example.com=http://a:8888,example.org=http://b:8888",
+		"READ_ONLY":                   "true",
+		"RETRIES":                     "3",
+		"STATUS_CLASSES":              "422=fatal,409=skip",
+		"RETRY_OPERATIONS":            "records,applychanges",
+		"NEGOTIATION_TIMEOUT":         "10s",
+		"ADJUST_ENDPOINTS_TIMEOUT":    "5s",
+		"TCP_KEEPALIVE":               "15s",
+		"DIAL_TIMEOUT":                "2s",
+		"REQUEST_TIMEOUT":             "1m",
+		"MAX_ENDPOINTS":               "100",
+		"BATCH_SIZE":                  "50",
+		"BATCH_BYTES":                 "1048576",
+		"DEFAULT_TTL":                 "300",
+		"ZERO_TTL":                    "zero",
+		"DEDUPLICATE_TARGETS":         "true",
+		"APPLY_ORDER":                 "delete,create,update",
+		"APPLY_METHOD":                "put",
+		"PAYLOAD_ORDER":               "delete",
+		"PREVIEW":                     "true",
+		"TRANSACTIONS":                "true",
+		"ERROR_ISOLATION":             "true",
+		"REQUEST_COMPRESSION":         "1024",
+		"PROTECTED_RECORDS":           "*.manual.example.com, mx.example.com",
+		"DELETE_GRACE_PERIOD":         "10m",
+		"TOMBSTONES":                  "true",
+		"ENVELOPE_CHANGES_FIELD":      "changeSet",
+		"ENVELOPE_CONTROLLER_ID":      "external-dns-1",
+		"ENVELOPE_RECORDS_FIELD":      "records",
+		"ERROR_LOG_THROTTLE":          "5m",
+		"JSON_PROPERTIES":             "webhook/config",
+		"AUDIT_ID_HEADER":             "X-Audit-Id",
+		"PLAN_REVIEW_FILE":            reviewFile,
+		"REGEX_DOMAIN_FILTER":         `\.staging\.example\.com$`,
+		"LABEL_VALUE_PATTERN":         `^[a-z]*$`,
+		"CASE_INSENSITIVE_PROPERTIES": "webhook/region",
+		"PROVIDER_SPECIFIC_ALLOWLIST": "webhook/zone-id,webhook/resource",
+		"TOKEN_FILE":                  tokenFile,
+		"HMAC_SECRET_FILE":            secretFile,
+		"TLS_SERVER_NAME":             "webhook.example.com",
+		"TLS_SPKI_PINS":               "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+	} {
+		t.Setenv(testEnvPrefix+name, value)
+	}
+
+	cfg, err := LoadEnvConfig(testEnvPrefix)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:9999", cfg.URL)
+	require.Equal(t, []WebhookRoute{{Domain: "a.example.com", URL: "http://a:8888"}, {Domain: "example.org", URL: "http://b:8888"}}, cfg.Routes)
+
+	p := WebhookProvider{client: &http.Client{}, transport: &http.Transport{}, dialer: newDialer()}
+	for _, opt := range cfg.Options {
+		opt(&p)
+	}
+	require.True(t, p.readOnly)
+	require.Equal(t, 3, p.maxRetries)
+	require.Equal(t, map[int]StatusClass{422: StatusClassFatal, 409: StatusClassSkip}, p.statusClasses)
+	require.Equal(t, map[RetryOperation]bool{RetryOperationRecords: true, RetryOperationApplyChanges: true}, p.retriedOperations)
+	require.Equal(t, 10*time.Second, p.negotiationTimeout)
+	require.Equal(t, 5*time.Second, p.adjustTimeout)
+	require.Equal(t, 15*time.Second, p.dialer.KeepAlive)
+	require.Equal(t, 2*time.Second, p.dialer.Timeout)
+	require.Equal(t, time.Minute, p.client.Timeout)
+	require.Equal(t, 100, p.maxEndpoints)
+	require.Equal(t, &batching{maxEndpoints: 50, maxBytes: 1 << 20}, p.batching)
+	require.Equal(t, endpoint.TTL(300), p.defaultTTL)
+	require.Equal(t, ZeroTTLSend, p.zeroTTL)
+	require.Len(t, p.endpointTransforms, 1)
+	require.Equal(t, []ApplyOperation{ApplyOperationDelete, ApplyOperationCreate, ApplyOperationUpdate}, p.applyOrder)
+	require.Equal(t, http.MethodPut, p.applyMethod)
+	require.Equal(t, []ApplyOperation{ApplyOperationDelete}, p.payloadOrder)
+	require.True(t, p.preview)
+	require.True(t, p.transactions)
+	require.True(t, p.errorIsolation)
+	require.Equal(t, &requestCompression{minBytes: 1024}, p.compression)
+	require.Equal(t, []string{"*.manual.example.com", "mx.example.com"}, p.protectedRecords)
+	require.Equal(t, 10*time.Minute, p.pendingDeletes.grace)
+	require.NotNil(t, p.tombstones)
+	require.Equal(t, &Envelope{ChangesField: "changeSet", MetadataField: "metadata", ControllerID: "external-dns-1", RecordsField: "records"}, p.envelope)
+	require.Equal(t, 5*time.Minute, p.errorLog.window)
+	require.Len(t, p.propertyComparators, 2)
+	require.Equal(t, map[string]interface{}{"X-Audit-Id": auditIDContextKey{}}, p.contextHeaders)
+	require.True(t, p.regexDomainFilter.Match("a.staging.example.com"))
+	require.Equal(t, reviewFile, p.planReview.path)
+	require.FileExists(t, reviewFile)
+	require.Equal(t, &labelValidation{value: regexp.MustCompile(`^[a-z]*$`)}, p.labelValidation)
+	require.False(t, p.regexDomainFilter.Match("a.example.com"))
+	require.True(t, p.propertyComparators["webhook/config"]("webhook/config", `{"a": 1}`, `{"a":1}`))
+	require.True(t, p.propertyComparators["webhook/region"]("webhook/region", "EU", "eu"))
+	require.Equal(t, map[string]bool{"webhook/zone-id": true, "webhook/resource": true}, p.providerSpecificAllowlist)
+	require.IsType(t, &TokenFileAuthenticator{}, p.authenticator)
+	require.Equal(t, []byte("hmac-secret"), p.signer.secret)
+	require.Equal(t, "webhook.example.com", p.transport.TLSClientConfig.ServerName)
+	require.NotNil(t, p.transport.TLSClientConfig.VerifyConnection)
+}
+
+func TestLoadEnvConfigEmpty(t *testing.T) {
+	cfg, err := LoadEnvConfig(testEnvPrefix)
+	require.NoError(t, err)
+	require.Empty(t, cfg.URL)
+	require.Empty(t, cfg.Options)
+}
+
+func TestLoadEnvConfigInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		env map[string]string
+		err string
+	}{
+		{env: map[string]string{"READ_ONLY": "maybe"}, err: `invalid value "maybe" for TEST_WEBHOOK_READ_ONLY: invalid syntax`},
+		{env: map[string]string{"RETRIES": "three"}, err: `invalid value "three" for TEST_WEBHOOK_RETRIES: invalid syntax`},
+		{env: map[string]string{"MAX_ENDPOINTS": "-1"}, err: `invalid value "-1" for TEST_WEBHOOK_MAX_ENDPOINTS: must not be negative`},
+		{env: map[string]string{"ADJUST_ENDPOINTS_TIMEOUT": "5"}, err: `invalid value "5" for TEST_WEBHOOK_ADJUST_ENDPOINTS_TIMEOUT: time: missing unit in duration "5"`},
+		{env: map[string]string{"APPLY_ORDER": "create,upsert"}, err: `invalid value "create,upsert" for TEST_WEBHOOK_APPLY_ORDER: unknown apply operation "upsert"`},
+		{env: map[string]string{"ROUTES": "a.example.com"}, err: `invalid value "a.example.com" for TEST_WEBHOOK_ROUTES: invalid webhook route "a.example.com", expected domain=url`},
+		{env: map[string]string{"RETRY_OPERATIONS": "all"}, err: `invalid value "all" for TEST_WEBHOOK_RETRY_OPERATIONS: unknown retry operation "all"`},
+		{env: map[string]string{"STATUS_CLASSES": "422=ignore"}, err: `invalid value "422=ignore" for TEST_WEBHOOK_STATUS_CLASSES: unknown status class "ignore"`},
+		{env: map[string]string{"ZERO_TTL": "none"}, err: `invalid value "none" for TEST_WEBHOOK_ZERO_TTL: unknown zero TTL mode "none"`},
+		{env: map[string]string{"REGEX_DOMAIN_FILTER": "("}, err: "invalid value \"(\" for TEST_WEBHOOK_REGEX_DOMAIN_FILTER: error parsing regexp: missing closing ): `(`"},
+		{env: map[string]string{"OAUTH2_CLIENT_ID": "id"}, err: "TEST_WEBHOOK_OAUTH2_CLIENT_ID, TEST_WEBHOOK_OAUTH2_CLIENT_SECRET and TEST_WEBHOOK_OAUTH2_TOKEN_URL must be set together"},
+		{env: map[string]string{"PLAN_REVIEW_FILE": "/nonexistent/review.json"}, err: `invalid value "/nonexistent/review.json" for TEST_WEBHOOK_PLAN_REVIEW_FILE: open /nonexistent/review.json: no such file or directory`},
+		{env: map[string]string{"CERT_FILE": "/tls.crt"}, err: "invalid TLS configuration: either both cert and key or none must be provided"},
+		{env: map[string]string{"TLS_SPKI_PINS": "sha256/YWJj"}, err: `invalid value "sha256/YWJj" for TEST_WEBHOOK_TLS_SPKI_PINS: invalid SPKI pin: expected a SHA-256 digest of 32 bytes, got 3 bytes`},
+		// the first malformed value is reported
+		{env: map[string]string{"RETRIES": "x", "DEFAULT_TTL": "y"}, err: `invalid value "x" for TEST_WEBHOOK_RETRIES: invalid syntax`},
+	} {
+		t.Run(tc.err, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(testEnvPrefix+name, value)
+			}
+			_, err := LoadEnvConfig(testEnvPrefix)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestNewProviderFromEnv(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	p, err := NewProviderFromEnv(testEnvPrefix, svr.URL)
+	require.NoError(t, err)
+	require.IsType(t, &WebhookProvider{}, p)
+
+	t.Setenv(testEnvPrefix+"ROUTES", "example.com="+svr.URL)
+	p, err = NewProviderFromEnv(testEnvPrefix, "http://localhost:8888")
+	require.NoError(t, err)
+	require.IsType(t, &WebhookRouter{}, p)
+
+	t.Setenv(testEnvPrefix+"URL", svr.URL)
+	_, err = NewProviderFromEnv(testEnvPrefix, "")
+	require.EqualError(t, err, "TEST_WEBHOOK_URL and TEST_WEBHOOK_ROUTES are mutually exclusive")
+}
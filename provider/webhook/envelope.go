@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Envelope configures the JSON document wrapping the payloads exchanged with a webhook backend
+// expecting e.g. {"changes": {...}, "metadata": {...}} rather than the bare plan.Changes
+type Envelope struct {
+	// ChangesField is the field holding the ApplyChanges body, "changes" if empty
+	ChangesField string
+	// MetadataField is the field holding the metadata of the ApplyChanges body, "metadata" if empty
+	MetadataField string
+	// ControllerID identifies the external-dns instance in the metadata, left out if empty
+	ControllerID string
+	// RecordsField is the field holding the endpoints of the Records responses, which are not unwrapped if empty
+	RecordsField string
+}
+
+// envelopeMetadata is the metadata of the wrapped ApplyChanges bodies
+type envelopeMetadata struct {
+	ControllerID string    `json:"controllerId,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// WebhookWithEnvelope wraps the ApplyChanges bodies in the changes field of e, next to the metadata
+// field holding the controller ID and the time of the request, and unwraps the endpoints of the Records
+// responses from the records field of e if it is set. By default the payloads are sent and read bare.
+func WebhookWithEnvelope(e Envelope) WebhookOption {
+	return func(p *WebhookProvider) {
+		if e.ChangesField == "" {
+			e.ChangesField = "changes"
+		}
+		if e.MetadataField == "" {
+			e.MetadataField = "metadata"
+		}
+		p.envelope = &e
+	}
+}
+
+// wrap wraps payload, the encoded ApplyChanges body, in the envelope
+func (e *Envelope) wrap(payload []byte, now time.Time) ([]byte, error) {
+	if e == nil {
+		return payload, nil
+	}
+	metadata, err := json.Marshal(envelopeMetadata{ControllerID: e.ControllerID, Timestamp: now.UTC().Truncate(time.Second)})
+	if err != nil {
+		return nil, err
+	}
+	b := new(bytes.Buffer)
+	err = json.NewEncoder(b).Encode(map[string]json.RawMessage{
+		e.ChangesField:  bytes.TrimSpace(payload),
+		e.MetadataField: metadata,
+	})
+	return b.Bytes(), err
+}
+
+// recordsCodec returns codec, unwrapping the endpoints from the records field when it is set
+func (e *Envelope) recordsCodec(codec Codec) Codec {
+	if e == nil || e.RecordsField == "" {
+		return codec
+	}
+	return envelopeCodec{Codec: codec, field: e.RecordsField}
+}
+
+// envelopeCodec decodes the endpoints held by a field of the wrapped responses with the embedded codec
+type envelopeCodec struct {
+	Codec
+	field string
+}
+
+func (c envelopeCodec) DecodeEndpoints(r io.Reader, endpoints *[]*endpoint.Endpoint) error {
+	var wrapped map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&wrapped); err != nil {
+		return err
+	}
+	payload, ok := wrapped[c.field]
+	if !ok {
+		return fmt.Errorf("missing field %q in the wrapped response", c.field)
+	}
+	return c.Codec.DecodeEndpoints(bytes.NewReader(payload), endpoints)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestEnvelope(t *testing.T) {
+	var stored []*endpoint.Endpoint
+	var metadata map[string]string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet:
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"records": stored, "count": len(stored)}))
+		default:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var wrapped struct {
+				ChangeSet plan.Changes      `json:"changeSet"`
+				Metadata  map[string]string `json:"metadata"`
+			}
+			require.NoError(t, json.Unmarshal(b, &wrapped))
+			stored = append(stored, wrapped.ChangeSet.Create...)
+			metadata = wrapped.Metadata
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer svr.Close()
+
+	clock := newFakeClock()
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithClock(clock),
+		WebhookWithEnvelope(Envelope{ChangesField: "changeSet", ControllerID: "external-dns-1", RecordsField: "records"}))
+	require.NoError(t, err)
+
+	created := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{created}}))
+	require.Equal(t, map[string]string{
+		"controllerId": "external-dns-1",
+		"timestamp":    clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+	}, metadata)
+
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "foo.example.com", records[0].DNSName)
+	require.Equal(t, endpoint.Targets{"1.2.3.4"}, records[0].Targets)
+}
+
+func TestEnvelopeMissingRecordsField(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithEnvelope(Envelope{RecordsField: "records"}))
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+}
+
+func TestEnvelopeDefaultFields(t *testing.T) {
+	var e *Envelope
+	payload, err := e.wrap([]byte(`{"Create":null}`), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, `{"Create":null}`, string(payload))
+
+	p := &WebhookProvider{}
+	WebhookWithEnvelope(Envelope{})(p)
+	payload, err = p.envelope.wrap([]byte(`{"Create":null}`+"\n"), time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, `{"changes":{"Create":null},"metadata":{"timestamp":"2024-01-02T03:04:05Z"}}`+"\n", string(payload))
+}
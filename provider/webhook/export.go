@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ExportRecords writes the records of the webhook owned by ownerID to w as a JSON list of endpoints,
+// e.g. for backups. Ownership is read from the owner label of the records.
+// Exporting doesn't change the state of the reconciliation, so it can run between two reconciliations.
+func (p WebhookProvider) ExportRecords(ctx context.Context, w io.Writer, ownerID string) error {
+	records, err := p.exportedRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get records to export: %w", err)
+	}
+	owned := []*endpoint.Endpoint{}
+	for _, e := range records {
+		if e.IsOwnedBy(ownerID) && !isTombstoned(e) {
+			owned = append(owned, e)
+		}
+	}
+	return json.NewEncoder(w).Encode(owned)
+}
+
+// exportedRecords gets the records like Records, without the side effects on the reconciliation:
+// the retry budget, pending deletions, tombstones, records of other owners, records to replace,
+// no-op cache and consistency expectations are left as they are
+func (p WebhookProvider) exportedRecords(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	if p.budget != nil && retryBudgetFromContext(ctx) == nil {
+		ctx = ContextWithRetryBudget(ctx, NewRetryBudget(p.budget.maxAttempts, p.budget.maxElapsed))
+	}
+	p.noopCache = nil
+	p.tombstones = nil
+	p.foreignRecords = nil
+	endpoints, _, _, err := p.fetchAllRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.normalizeRecords(endpoints)
+}
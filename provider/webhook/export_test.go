@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestExportRecords(t *testing.T) {
+	svr := newPayloadServer(`[
+		{"dnsName": "owned.example.com", "recordType": "A", "targets": ["1.2.3.4"], "labels": {"owner": "default"}},
+		{"dnsName": "other.example.com", "recordType": "A", "targets": ["1.2.3.4"], "labels": {"owner": "other"}},
+		{"dnsName": "unowned.example.com", "recordType": "A", "targets": ["1.2.3.4"]}
+	]`)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, provider.ExportRecords(context.TODO(), &b, "default"))
+
+	var exported []*endpoint.Endpoint
+	require.NoError(t, json.Unmarshal(b.Bytes(), &exported))
+	require.Len(t, exported, 1)
+	require.Equal(t, "owned.example.com", exported[0].DNSName)
+	require.Equal(t, "default", exported[0].Labels[endpoint.OwnerLabelKey])
+
+	// nothing owned is exported as an empty list
+	b.Reset()
+	require.NoError(t, provider.ExportRecords(context.TODO(), &b, "nobody"))
+	require.JSONEq(t, `[]`, b.String())
+}
+
+func TestExportRecordsBetweenReconciles(t *testing.T) {
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+	record := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	record.Labels[endpoint.OwnerLabelKey] = "default"
+	webhook.records[record.Key()] = record
+
+	clock := newFakeClock()
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDeleteGracePeriod(time.Minute), WebhookWithClock(clock))
+	require.NoError(t, err)
+
+	// the deletion is deferred
+	require.True(t, reconcile(t, provider, "default"))
+	require.Len(t, webhook.state(), 1)
+	clock.After(time.Minute)
+
+	// exporting doesn't count as a reconciliation, which would start the grace period over
+	var b bytes.Buffer
+	require.NoError(t, provider.ExportRecords(context.TODO(), &b, "default"))
+	require.True(t, reconcile(t, provider, "default"))
+	require.Empty(t, webhook.state())
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithDeleteGracePeriod defers the deletion of records until they have been planned for deletion
+// for at least grace, so that records of flapping sources are not deleted and created again.
+// A deferred deletion is cancelled when the record is no longer planned for deletion.
+func WebhookWithDeleteGracePeriod(grace time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.pendingDeletes = &pendingDeletes{grace: grace, entries: map[endpoint.EndpointKey]pendingDelete{}}
+	}
+}
+
+// pendingDeletes tracks the deletions deferred across reconciliations.
+// Reconciliations are counted by the calls to Records, so that a record not planned for deletion
+// in a reconciliation without changes to apply cancels its deferred deletion as well.
+type pendingDeletes struct {
+	mu         sync.Mutex
+	grace      time.Duration
+	reconciles int64
+	entries    map[endpoint.EndpointKey]pendingDelete
+}
+
+type pendingDelete struct {
+	// since is when the record was first planned for deletion
+	since time.Time
+	// reconcile is the last reconciliation in which the record was planned for deletion
+	reconcile int64
+}
+
+// reconcileStarted marks the start of a reconciliation
+func (d *pendingDeletes) reconcileStarted() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reconciles++
+}
+
+// withhold returns changes without the deletions planned for less than the grace period at now
+func (d *pendingDeletes) withhold(changes *plan.Changes, now time.Time) *plan.Changes {
+	if d == nil || changes == nil {
+		return changes
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	planned := make(map[endpoint.EndpointKey]bool, len(changes.Delete))
+	var deletes []*endpoint.Endpoint
+	for _, e := range changes.Delete {
+		key := e.Key()
+		planned[key] = true
+		entry, ok := d.entries[key]
+		if !ok || entry.reconcile < d.reconciles-1 {
+			entry.since = now
+		}
+		entry.reconcile = d.reconciles
+		d.entries[key] = entry
+		if now.Sub(entry.since) >= d.grace {
+			deletes = append(deletes, e)
+			continue
+		}
+		log.Infof("Deferring deletion of %s %s for %s", e.RecordType, e.DNSName, d.grace-now.Sub(entry.since))
+	}
+	// the records no longer planned for deletion are kept
+	for key := range d.entries {
+		if !planned[key] {
+			delete(d.entries, key)
+		}
+	}
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateOld: changes.UpdateOld,
+		UpdateNew: changes.UpdateNew,
+		Delete:    deletes,
+	}
+}
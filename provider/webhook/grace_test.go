@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestDeleteGracePeriod(t *testing.T) {
+	var deleted []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			for _, e := range changes.Delete {
+				deleted = append(deleted, e.DNSName)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+
+	clock := newFakeClock()
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDeleteGracePeriod(time.Minute), WebhookWithClock(clock))
+	require.NoError(t, err)
+
+	flapping := endpoint.NewEndpoint("flapping.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	other := endpoint.NewEndpoint("other.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	reconcile := func(changes *plan.Changes) {
+		_, err := provider.Records(context.TODO())
+		require.NoError(t, err)
+		if changes != nil {
+			require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+		}
+		clock.After(20 * time.Second)
+	}
+
+	// the deletion is deferred
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	require.Empty(t, deleted)
+	// the record is back within the grace period, leaving nothing to apply
+	reconcile(nil)
+	// planned for deletion again, the grace period starts over
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	require.Empty(t, deleted)
+	// back again along with other changes
+	reconcile(&plan.Changes{Create: []*endpoint.Endpoint{other}})
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	require.Empty(t, deleted)
+
+	// deleted once planned for deletion during the whole grace period
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	require.Empty(t, deleted)
+	reconcile(&plan.Changes{Delete: []*endpoint.Endpoint{flapping}})
+	require.Equal(t, []string{"flapping.example.com"}, deleted)
+}
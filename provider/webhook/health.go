@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrWebhookUnreachable is returned by Ping when no response could be received from the webhook,
+	// e.g. on connection or TLS errors. The underlying error is wrapped.
+	ErrWebhookUnreachable = errors.New("webhook unreachable")
+	// ErrWebhookUnauthorized is returned by Ping when the webhook rejects the credentials of the request
+	ErrWebhookUnauthorized = errors.New("webhook unauthorized")
+)
+
+// Ping checks that the webhook is reachable and accepts the requests of the provider,
+// without fetching the records. It makes a GET request to the negotiation endpoint.
+func (p WebhookProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.remoteServerURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(acceptHeader, p.accept())
+	p.signer.signRequest(req, nil)
+	if err := p.authenticate(req); err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWebhookUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: code %d", ErrWebhookUnauthorized, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("webhook ping failed with code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	var status, recordsCalls int32
+	atomic.StoreInt32(&status, http.StatusOK)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			atomic.AddInt32(&recordsCalls, 1)
+		}
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(`{}`))
+	}))
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Ping(context.TODO()))
+
+	atomic.StoreInt32(&status, http.StatusForbidden)
+	require.ErrorIs(t, provider.Ping(context.TODO()), ErrWebhookUnauthorized)
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	err = provider.Ping(context.TODO())
+	require.EqualError(t, err, "webhook ping failed with code 503")
+	require.NotErrorIs(t, err, ErrWebhookUnreachable)
+
+	svr.Close()
+	require.ErrorIs(t, provider.Ping(context.TODO()), ErrWebhookUnreachable)
+	require.Zero(t, atomic.LoadInt32(&recordsCalls))
+}
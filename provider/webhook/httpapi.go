@@ -101,15 +101,9 @@ func (p *WebhookServer) NegotiateHandler(w http.ResponseWriter, req *http.Reques
 	json.NewEncoder(w).Encode(p.Provider.GetDomainFilter())
 }
 
-// StartHTTPApi starts a HTTP server given any provider.
-// the function takes an optional channel as input which is used to signal that the server has started.
-// The server will listen on port `providerPort`.
-// The server will respond to the following endpoints:
-// - / (GET): initialization, negotiates headers and returns the domain filter
-// - /records (GET): returns the current records
-// - /records (POST): applies the changes
-// - /adjustendpoints (POST): executes the AdjustEndpoints method
-func StartHTTPApi(provider provider.Provider, startedChan chan struct{}, readTimeout, writeTimeout time.Duration, providerPort string) {
+// NewHandler returns the handler serving the webhook API backed by any provider, including a WebhookProvider,
+// so that ExternalDNS instances can be chained. It serves the endpoints listed in StartHTTPApi.
+func NewHandler(provider provider.Provider) http.Handler {
 	p := WebhookServer{
 		Provider: provider,
 	}
@@ -118,10 +112,21 @@ func StartHTTPApi(provider provider.Provider, startedChan chan struct{}, readTim
 	m.HandleFunc("/", p.NegotiateHandler)
 	m.HandleFunc("/records", p.RecordsHandler)
 	m.HandleFunc("/adjustendpoints", p.AdjustEndpointsHandler)
+	return m
+}
 
+// StartHTTPApi starts a HTTP server given any provider.
+// the function takes an optional channel as input which is used to signal that the server has started.
+// The server will listen on port `providerPort`.
+// The server will respond to the following endpoints:
+// - / (GET): initialization, negotiates headers and returns the domain filter
+// - /records (GET): returns the current records
+// - /records (POST): applies the changes
+// - /adjustendpoints (POST): executes the AdjustEndpoints method
+func StartHTTPApi(provider provider.Provider, startedChan chan struct{}, readTimeout, writeTimeout time.Duration, providerPort string) {
 	s := &http.Server{
 		Addr:         providerPort,
-		Handler:      m,
+		Handler:      NewHandler(provider),
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 	}
@@ -30,6 +30,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
 )
 
 var records []*endpoint.Endpoint
@@ -276,3 +277,35 @@ func TestStartHTTPApi(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, df.UnmarshalJSON(b))
 }
+
+func TestChainedWebhookProviders(t *testing.T) {
+	backend := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	upstream := httptest.NewServer(NewHandler(backend))
+	defer upstream.Close()
+
+	// the middle instance consumes the upstream webhook and exposes it again
+	middle, err := NewWebhookProvider(upstream.URL)
+	require.NoError(t, err)
+	downstream := httptest.NewServer(NewHandler(middle))
+	defer downstream.Close()
+
+	provider, err := NewWebhookProvider(downstream.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "foo.example.com", endpoints[0].DNSName)
+
+	adjusted, err := provider.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+
+	backendRecords, err := backend.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, backendRecords, 1)
+	require.Equal(t, endpoint.Targets{"1.2.3.4"}, backendRecords[0].Targets)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// recordsTokenHeader is set by webhooks supporting incremental records on the response to GET /records,
+// to the token to send with the next request to get the records changed since then
+const recordsTokenHeader = "X-Records-Token"
+
+// RecordsSince returns the records changed since token along with the token to use for the next call.
+// An empty token returns all the records. When the webhook does not advertise the incremental capability,
+// all the records are returned by Records with an empty token.
+func (p WebhookProvider) RecordsSince(ctx context.Context, token string) ([]*endpoint.Endpoint, string, error) {
+	if !p.capabilities.Incremental {
+		endpoints, err := p.Records(ctx)
+		return endpoints, "", err
+	}
+
+	records := p.remoteServerURL.JoinPath("records")
+	query := url.Values{}
+	if token != "" {
+		query.Set("since", token)
+	}
+	records.RawQuery = p.recordsQuery(query)
+	u := records.String()
+	resp, err := p.doWithRetry(ctx, RetryOperationRecords, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(acceptHeader, p.accept())
+		return req, nil
+	})
+	if err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to perform request: %s", err.Error())
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		recordsErrorsGauge.Inc()
+		log.WithFields(log.Fields{"path": resp.Request.URL.Path, "status": resp.StatusCode}).Debug("Failed to get records")
+		return nil, "", p.newStatusError(resp.StatusCode, fmt.Sprintf("failed to get records with code %d", resp.StatusCode))
+	}
+
+	codec, err := p.responseCodec(resp.Header.Get(contentTypeHeader))
+	if err != nil {
+		recordsErrorsGauge.Inc()
+		return nil, "", err
+	}
+	endpoints := []*endpoint.Endpoint{}
+	if err := p.decodeEndpoints(p.envelope.recordsCodec(codec), resp, &endpoints); err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to decode response body: %s", err.Error())
+		return nil, "", err
+	}
+	endpoints, err = p.normalizeRecords(endpoints)
+	if err != nil {
+		return nil, "", err
+	}
+	if token == "" {
+		p.storeRecordsCount(endpoints)
+	}
+	next := resp.Header.Get(recordsTokenHeader)
+	log.WithFields(log.Fields{"path": resp.Request.URL.Path, "since": token, "endpoints": len(endpoints)}).Debug("Received changed records")
+	return endpoints, next, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsSince(t *testing.T) {
+	var queries []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"capabilities": {"incremental": true}}`))
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("since") {
+		case "":
+			w.Header().Set(recordsTokenHeader, "1")
+			w.Write([]byte(`[{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"]}, {"dnsName": "b.example.com", "recordType": "A", "targets": ["1.2.3.4"]}]`))
+		case "1":
+			w.Header().Set(recordsTokenHeader, "2")
+			w.Write([]byte(`[{"dnsName": "b.example.com", "recordType": "A", "targets": ["5.6.7.8"]}]`))
+		default:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	endpoints, token, err := provider.RecordsSince(context.TODO(), "")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	require.Equal(t, "1", token)
+
+	endpoints, token, err = provider.RecordsSince(context.TODO(), token)
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "b.example.com", endpoints[0].DNSName)
+	require.Equal(t, "2", token)
+
+	_, _, err = provider.RecordsSince(context.TODO(), "expired")
+	require.EqualError(t, err, "failed to get records with code 410")
+	require.Equal(t, []string{"", "since=1", "since=expired"}, queries)
+}
+
+func TestRecordsSinceFallback(t *testing.T) {
+	svr := newPayloadServer(`[{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"]}]`)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	endpoints, token, err := provider.RecordsSince(context.TODO(), "1")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.Empty(t, token)
+}
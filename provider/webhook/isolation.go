@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// maxRejectedBodySize limits how much of a 207 response is read to find the rejected endpoints
+const maxRejectedBodySize = 1 << 20
+
+// rejectedEndpoint is an endpoint reported as invalid in a 207 response to POST /records
+type rejectedEndpoint struct {
+	DNSName       string `json:"dnsName"`
+	RecordType    string `json:"recordType"`
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// applyStatusError is returned when the webhook responds to POST /records with an unexpected status code
+type applyStatusError struct {
+	statusCode int
+	class      StatusClass
+	rejected   map[endpoint.EndpointKey]string
+}
+
+func (e *applyStatusError) Error() string {
+	return fmt.Sprintf("failed to apply changes with code %d", e.statusCode)
+}
+
+func (e *applyStatusError) Unwrap() error {
+	return classError(e.class)
+}
+
+// newApplyStatusError reads the rejected endpoints from a 207 response, if any
+func newApplyStatusError(resp *http.Response, class StatusClass) *applyStatusError {
+	err := &applyStatusError{statusCode: resp.StatusCode, class: class}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return err
+	}
+	var body struct {
+		Failed []rejectedEndpoint `json:"failed"`
+	}
+	if decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxRejectedBodySize)).Decode(&body); decodeErr != nil {
+		log.Debugf("Failed to decode rejected endpoints: %s", decodeErr.Error())
+		return err
+	}
+	err.rejected = make(map[endpoint.EndpointKey]string, len(body.Failed))
+	for _, r := range body.Failed {
+		err.rejected[endpoint.EndpointKey{DNSName: r.DNSName, RecordType: r.RecordType, SetIdentifier: r.SetIdentifier}] = r.Error
+	}
+	return err
+}
+
+// WebhookWithErrorIsolation retries the changes rejected by the webhook without the invalid endpoints,
+// so that a single bad endpoint doesn't block all the other changes.
+// The invalid endpoints are read from a 207 response, or found by bisecting the changes refused with a fatal
+// status code, or with a 5xx status code once the retries are used up.
+// ApplyChanges still fails, reporting only the rejected endpoints.
+func WebhookWithErrorIsolation() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.errorIsolation = true
+	}
+}
+
+// changeUnit is a change that can't be split further: a created or deleted endpoint, or an updated pair
+type changeUnit struct {
+	old, new *endpoint.Endpoint
+	op       ApplyOperation
+}
+
+func (u changeUnit) endpoint() *endpoint.Endpoint {
+	if u.new != nil {
+		return u.new
+	}
+	return u.old
+}
+
+// rejectedChange is a change unit rejected by the webhook
+type rejectedChange struct {
+	unit   changeUnit
+	reason string
+}
+
+// changeUnits splits the changes into units, returning false if the updates can't be paired
+func changeUnits(changes *plan.Changes) ([]changeUnit, bool) {
+	if len(changes.UpdateOld) != len(changes.UpdateNew) {
+		return nil, false
+	}
+	var units []changeUnit
+	for _, e := range changes.Create {
+		units = append(units, changeUnit{new: e, op: ApplyOperationCreate})
+	}
+	for i := range changes.UpdateNew {
+		units = append(units, changeUnit{old: changes.UpdateOld[i], new: changes.UpdateNew[i], op: ApplyOperationUpdate})
+	}
+	for _, e := range changes.Delete {
+		units = append(units, changeUnit{old: e, op: ApplyOperationDelete})
+	}
+	return units, true
+}
+
+// unitsChanges joins change units back into changes
+func unitsChanges(units []changeUnit) *plan.Changes {
+	changes := &plan.Changes{}
+	for _, u := range units {
+		switch u.op {
+		case ApplyOperationCreate:
+			changes.Create = append(changes.Create, u.new)
+		case ApplyOperationUpdate:
+			changes.UpdateOld = append(changes.UpdateOld, u.old)
+			changes.UpdateNew = append(changes.UpdateNew, u.new)
+		case ApplyOperationDelete:
+			changes.Delete = append(changes.Delete, u.old)
+		}
+	}
+	return changes
+}
+
+// isRejection reports whether err is the webhook rejecting the payload, setting statusErr.
+// A server error still returned once the retries are used up may be caused by an endpoint, so it is isolated,
+// while the other transient failures, e.g. 429, are not, since the endpoints aren't at fault.
+func isRejection(err error, statusErr **applyStatusError) bool {
+	if !errors.As(err, statusErr) {
+		return false
+	}
+	switch (*statusErr).class {
+	case StatusClassFatal:
+		return true
+	case StatusClassRetryable:
+		return (*statusErr).statusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// postChangesIsolated sends the changes like postChanges, isolating the endpoints rejected by the webhook
+func (p WebhookProvider) postChangesIsolated(ctx context.Context, changes *plan.Changes, extraHeaders map[string]string) error {
+	err := p.postChanges(ctx, changes, extraHeaders)
+	var statusErr *applyStatusError
+	if changes == nil || !isRejection(err, &statusErr) {
+		return err
+	}
+	units, ok := changeUnits(changes)
+	if !ok {
+		return err
+	}
+
+	var rejected []rejectedChange
+	if err := p.isolateRejected(ctx, units, statusErr, extraHeaders, &rejected); err != nil {
+		return err
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(rejected))
+	for _, r := range rejected {
+		e := r.unit.endpoint()
+		log.WithFields(log.Fields{
+			"dnsName":    e.DNSName,
+			"recordType": e.RecordType,
+			"operation":  r.unit.op,
+		}).Errorf("Webhook rejected change: %s", r.reason)
+		names = append(names, e.DNSName)
+	}
+	return fmt.Errorf("webhook rejected the changes of %d endpoints: %s", len(rejected), strings.Join(names, ", "))
+}
+
+// isolateRejected splits units, rejected as a whole with statusErr, and sends again the ones not found invalid
+func (p WebhookProvider) isolateRejected(ctx context.Context, units []changeUnit, statusErr *applyStatusError, extraHeaders map[string]string, rejected *[]rejectedChange) error {
+	if len(statusErr.rejected) > 0 {
+		var valid []changeUnit
+		found := false
+		for _, u := range units {
+			if reason, ok := statusErr.rejected[u.endpoint().Key()]; ok {
+				found = true
+				if reason == "" {
+					reason = statusErr.Error()
+				}
+				*rejected = append(*rejected, rejectedChange{unit: u, reason: reason})
+				continue
+			}
+			valid = append(valid, u)
+		}
+		if found {
+			return p.retryIsolated(ctx, valid, extraHeaders, rejected)
+		}
+	}
+	if len(units) == 1 {
+		*rejected = append(*rejected, rejectedChange{unit: units[0], reason: statusErr.Error()})
+		return nil
+	}
+	mid := len(units) / 2
+	if err := p.retryIsolated(ctx, units[:mid], extraHeaders, rejected); err != nil {
+		return err
+	}
+	return p.retryIsolated(ctx, units[mid:], extraHeaders, rejected)
+}
+
+// retryIsolated sends units, isolating the rejected ones if the webhook refuses them
+func (p WebhookProvider) retryIsolated(ctx context.Context, units []changeUnit, extraHeaders map[string]string, rejected *[]rejectedChange) error {
+	if len(units) == 0 {
+		return nil
+	}
+	err := p.postChanges(ctx, unitsChanges(units), extraHeaders)
+	var statusErr *applyStatusError
+	if !isRejection(err, &statusErr) {
+		return err
+	}
+	return p.isolateRejected(ctx, units, statusErr, extraHeaders, rejected)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// newRejectingServer returns a webhook server refusing with status any changes containing the bad endpoint
+// and storing the names of the endpoints of the accepted changes
+func newRejectingServer(t *testing.T, bad string, status int, applied *[]string, posts *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		*posts++
+		var changes plan.Changes
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		require.Equal(t, len(changes.UpdateOld), len(changes.UpdateNew))
+		var names []string
+		for _, e := range append(append(changes.Create, changes.UpdateNew...), changes.Delete...) {
+			names = append(names, e.DNSName)
+		}
+		for _, name := range names {
+			if name != bad {
+				continue
+			}
+			w.WriteHeader(status)
+			if status == http.StatusMultiStatus {
+				w.Write([]byte(`{"failed": [{"dnsName": "` + bad + `", "recordType": "A", "error": "invalid target"}]}`))
+			}
+			return
+		}
+		*applied = append(*applied, names...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func isolationChanges() *plan.Changes {
+	return &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "invalid"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "5.6.7.8")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+}
+
+func TestErrorIsolationMultiStatus(t *testing.T) {
+	var applied []string
+	posts := 0
+	svr := newRejectingServer(t, "bad.example.com", http.StatusMultiStatus, &applied, &posts)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithErrorIsolation())
+	require.NoError(t, err)
+
+	err = provider.ApplyChanges(context.TODO(), isolationChanges())
+	require.EqualError(t, err, "webhook rejected the changes of 1 endpoints: bad.example.com")
+	require.Equal(t, 2, posts)
+	require.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}, applied)
+}
+
+func TestErrorIsolationBisection(t *testing.T) {
+	var applied []string
+	posts := 0
+	svr := newRejectingServer(t, "bad.example.com", http.StatusBadRequest, &applied, &posts)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithErrorIsolation())
+	require.NoError(t, err)
+
+	err = provider.ApplyChanges(context.TODO(), isolationChanges())
+	require.EqualError(t, err, "webhook rejected the changes of 1 endpoints: bad.example.com")
+	require.ElementsMatch(t, []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}, applied)
+
+	// without the option, the whole batch fails
+	applied, posts = nil, 0
+	provider, err = NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	err = provider.ApplyChanges(context.TODO(), isolationChanges())
+	require.EqualError(t, err, "failed to apply changes with code 400")
+	require.Equal(t, 1, posts)
+	require.Empty(t, applied)
+}
+
+func TestErrorIsolationTransientFailure(t *testing.T) {
+	posts, status := 0, http.StatusInternalServerError
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		posts++
+		if posts == 1 {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	// a server error is bisected, the split changes being all applied
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithErrorIsolation())
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), isolationChanges()))
+	require.Equal(t, 3, posts)
+
+	// a rate limit isn't bisected
+	posts, status = 0, http.StatusTooManyRequests
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithErrorIsolation())
+	require.NoError(t, err)
+	err = provider.ApplyChanges(context.TODO(), isolationChanges())
+	require.ErrorIs(t, err, ErrWebhookRetryable)
+	require.Equal(t, 1, posts)
+}
+
+func TestErrorIsolationServerError(t *testing.T) {
+	var applied []string
+	posts := 0
+	svr := newRejectingServer(t, "bad.example.com", http.StatusInternalServerError, &applied, &posts)
+	defer svr.Close()
+
+	// the batch keeps failing with 500 because of one endpoint, including after the retries
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithErrorIsolation(), WebhookWithRetriedOperations(RetryOperationApplyChanges), WebhookWithRetries(1), WebhookWithClock(newFakeClock()))
+	require.NoError(t, err)
+
+	err = provider.ApplyChanges(context.TODO(), isolationChanges())
+	require.EqualError(t, err, "webhook rejected the changes of 1 endpoints: bad.example.com")
+	require.ElementsMatch(t, []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}, applied)
+}
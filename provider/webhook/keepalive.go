@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	defaultDialTimeout  = 30 * time.Second
+	defaultTCPKeepAlive = 30 * time.Second
+)
+
+// newDialer returns the dialer of the connections to the webhook
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultTCPKeepAlive,
+	}
+}
+
+// WebhookWithTCPKeepAlive sets the interval of the TCP keep-alive probes of the connections to the webhook,
+// so that connections silently dropped, e.g. by a NAT, are detected and replaced.
+// It defaults to 30s, a negative interval disables the keep-alive probes.
+func WebhookWithTCPKeepAlive(interval time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.dialer.KeepAlive = interval
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPKeepAlive(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.Equal(t, defaultTCPKeepAlive, provider.dialer.KeepAlive)
+
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithTCPKeepAlive(10*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, provider.dialer.KeepAlive)
+	require.Equal(t, defaultDialTimeout, provider.dialer.Timeout)
+
+	// the connections are dialed with the configured dialer
+	conn, err := provider.transport.DialContext(context.TODO(), "tcp", svr.Listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+}
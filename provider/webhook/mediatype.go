@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	mediaType      = "application/external.dns.webhook+json"
+	defaultVersion = "1"
+)
+
+// Codec serializes the payloads exchanged with the webhook for one version of the media type
+type Codec interface {
+	EncodeEndpoints(w io.Writer, endpoints []*endpoint.Endpoint) error
+	DecodeEndpoints(r io.Reader, endpoints *[]*endpoint.Endpoint) error
+	EncodeChanges(w io.Writer, changes *plan.Changes) error
+}
+
+// WebhookWithMediaTypeVersion accepts an additional version of the media type, serialized with codec.
+// The versions are sent in the Accept header, the last added being preferred,
+// and each response is decoded according to the version of its Content-Type.
+func WebhookWithMediaTypeVersion(version string, codec Codec) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.codecs == nil {
+			p.codecs = map[string]Codec{}
+		}
+		if _, ok := p.codecs[version]; !ok {
+			p.versions = append([]string{version}, p.versions...)
+		}
+		p.codecs[version] = codec
+	}
+}
+
+func mediaTypeWithVersion(version string) string {
+	return mediaType + ";version=" + version
+}
+
+// versionOf returns the version of the webhook media type contentType
+func versionOf(contentType string) (string, error) {
+	t, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	if t != mediaType || params["version"] == "" {
+		return "", fmt.Errorf("unsupported media type %s", contentType)
+	}
+	return params["version"], nil
+}
+
+// accept returns the value of the Accept header listing all the accepted versions
+func (p WebhookProvider) accept() string {
+	versions := p.versions
+	if len(versions) == 0 {
+		versions = []string{defaultVersion}
+	}
+	mediaTypes := make([]string, 0, len(versions))
+	for _, v := range versions {
+		mediaTypes = append(mediaTypes, mediaTypeWithVersion(v))
+	}
+	return strings.Join(mediaTypes, ", ")
+}
+
+// contentType returns the media type of the negotiated version
+func (p WebhookProvider) contentType() string {
+	return mediaTypeWithVersion(p.negotiatedVersion())
+}
+
+// Version returns the version of the webhook media type negotiated with the webhook
+func (p WebhookProvider) Version() string {
+	return p.negotiatedVersion()
+}
+
+func (p WebhookProvider) negotiatedVersion() string {
+	if p.version == "" {
+		return defaultVersion
+	}
+	return p.version
+}
+
+func (p WebhookProvider) codecForVersion(version string) (Codec, bool) {
+	if c, ok := p.codecs[version]; ok {
+		return c, true
+	}
+	if version == defaultVersion {
+		return p.fieldNaming, true
+	}
+	return nil, false
+}
+
+// codec returns the codec of the negotiated version
+func (p WebhookProvider) codec() Codec {
+	c, _ := p.codecForVersion(p.negotiatedVersion())
+	return c
+}
+
+// responseCodec returns the codec matching the Content-Type of a response,
+// falling back to the negotiated version when the response is not of the webhook media type
+func (p WebhookProvider) responseCodec(contentType string) (Codec, error) {
+	version, err := versionOf(contentType)
+	if err != nil {
+		return p.codec(), nil
+	}
+	c, ok := p.codecForVersion(version)
+	if !ok {
+		return nil, fmt.Errorf("unsupported media type version %s", version)
+	}
+	return c, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionedServer(t *testing.T, version string, accept *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			*accept = r.Header.Get(acceptHeader)
+			w.Header().Set(contentTypeHeader, mediaTypeWithVersion(version))
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		w.Header().Set(contentTypeHeader, mediaTypeWithVersion(version))
+		if version == "2" {
+			w.Write([]byte(`[{"dns_name":"test.example.com","record_type":"A","targets":["1.2.3.4"]}]`))
+			return
+		}
+		w.Write([]byte(`[{"dnsName":"test.example.com","recordType":"A","targets":["1.2.3.4"]}]`))
+	}))
+}
+
+func TestMediaTypeVersions(t *testing.T) {
+	for _, version := range []string{"1", "2"} {
+		t.Run("version "+version, func(t *testing.T) {
+			var accept string
+			svr := newVersionedServer(t, version, &accept)
+			defer svr.Close()
+
+			p, err := NewWebhookProvider(svr.URL, WebhookWithMediaTypeVersion("2", FieldNamingSnakeCase))
+			require.NoError(t, err)
+			require.Equal(t, mediaTypeWithVersion("2")+", "+mediaTypeWithVersion("1"), accept)
+			require.Equal(t, mediaTypeWithVersion(version), p.contentType())
+			require.Equal(t, version, p.Version())
+
+			endpoints, err := p.Records(context.TODO())
+			require.NoError(t, err)
+			require.Len(t, endpoints, 1)
+			require.Equal(t, "test.example.com", endpoints[0].DNSName)
+		})
+	}
+}
+
+func TestMediaTypeUnsupportedVersion(t *testing.T) {
+	var accept string
+	svr := newVersionedServer(t, "2", &accept)
+	defer svr.Close()
+
+	_, err := NewWebhookProvider(svr.URL)
+	require.EqualError(t, err, "wrong content type returned from server: "+mediaTypeWithVersion("2"))
+	require.Equal(t, mediaTypeFormatAndVersion, accept)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// FieldNaming is the naming policy of the JSON fields exchanged with the webhook
+type FieldNaming string
+
+const (
+	// FieldNamingCamelCase uses the field names of the Go types, e.g. recordTTL. This is the default.
+	FieldNamingCamelCase FieldNaming = "camelCase"
+	// FieldNamingSnakeCase uses snake_case field names, e.g. record_ttl
+	FieldNamingSnakeCase FieldNaming = "snake_case"
+)
+
+// snakeCaseEndpoint mirrors endpoint.Endpoint with snake_case JSON field names.
+// Its fields must be kept identical to endpoint.Endpoint so that both types are convertible.
+type snakeCaseEndpoint struct {
+	DNSName          string                    `json:"dns_name,omitempty"`
+	Targets          endpoint.Targets          `json:"targets,omitempty"`
+	RecordType       string                    `json:"record_type,omitempty"`
+	SetIdentifier    string                    `json:"set_identifier,omitempty"`
+	RecordTTL        endpoint.TTL              `json:"record_ttl,omitempty"`
+	Labels           endpoint.Labels           `json:"labels,omitempty"`
+	ProviderSpecific endpoint.ProviderSpecific `json:"provider_specific,omitempty"`
+}
+
+// snakeCaseChanges mirrors plan.Changes with snake_case JSON field names
+type snakeCaseChanges struct {
+	Create    []*snakeCaseEndpoint `json:"create"`
+	UpdateOld []*snakeCaseEndpoint `json:"update_old"`
+	UpdateNew []*snakeCaseEndpoint `json:"update_new"`
+	Delete    []*snakeCaseEndpoint `json:"delete"`
+}
+
+// WebhookWithFieldNaming sets the naming policy of the JSON fields sent to and read from the webhook
+func WebhookWithFieldNaming(naming FieldNaming) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.fieldNaming = naming
+	}
+}
+
+func toSnakeCase(endpoints []*endpoint.Endpoint) []*snakeCaseEndpoint {
+	if endpoints == nil {
+		return nil
+	}
+	s := make([]*snakeCaseEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		se := snakeCaseEndpoint(*e)
+		s = append(s, &se)
+	}
+	return s
+}
+
+func fromSnakeCase(s []*snakeCaseEndpoint) []*endpoint.Endpoint {
+	if s == nil {
+		return nil
+	}
+	endpoints := make([]*endpoint.Endpoint, 0, len(s))
+	for _, se := range s {
+		e := endpoint.Endpoint(*se)
+		endpoints = append(endpoints, &e)
+	}
+	return endpoints
+}
+
+// EncodeEndpoints writes the JSON encoding of endpoints to w
+func (n FieldNaming) EncodeEndpoints(w io.Writer, endpoints []*endpoint.Endpoint) error {
+	if n == FieldNamingSnakeCase {
+		return json.NewEncoder(w).Encode(toSnakeCase(endpoints))
+	}
+	return json.NewEncoder(w).Encode(endpoints)
+}
+
+// DecodeEndpoints reads JSON encoded endpoints from r
+func (n FieldNaming) DecodeEndpoints(r io.Reader, endpoints *[]*endpoint.Endpoint) error {
+	if n == FieldNamingSnakeCase {
+		s := []*snakeCaseEndpoint{}
+		if err := json.NewDecoder(r).Decode(&s); err != nil {
+			return err
+		}
+		*endpoints = fromSnakeCase(s)
+		return nil
+	}
+	return json.NewDecoder(r).Decode(endpoints)
+}
+
+// EncodeChanges writes the JSON encoding of changes to w
+func (n FieldNaming) EncodeChanges(w io.Writer, changes *plan.Changes) error {
+	if n == FieldNamingSnakeCase && changes != nil {
+		return json.NewEncoder(w).Encode(snakeCaseChanges{
+			Create:    toSnakeCase(changes.Create),
+			UpdateOld: toSnakeCase(changes.UpdateOld),
+			UpdateNew: toSnakeCase(changes.UpdateNew),
+			Delete:    toSnakeCase(changes.Delete),
+		})
+	}
+	return json.NewEncoder(w).Encode(changes)
+}
+
+// DecodeChanges reads JSON encoded changes from r
+func (n FieldNaming) DecodeChanges(r io.Reader, changes *plan.Changes) error {
+	if n == FieldNamingSnakeCase {
+		s := snakeCaseChanges{}
+		if err := json.NewDecoder(r).Decode(&s); err != nil {
+			return err
+		}
+		*changes = plan.Changes{
+			Create:    fromSnakeCase(s.Create),
+			UpdateOld: fromSnakeCase(s.UpdateOld),
+			UpdateNew: fromSnakeCase(s.UpdateNew),
+			Delete:    fromSnakeCase(s.Delete),
+		}
+		return nil
+	}
+	return json.NewDecoder(r).Decode(changes)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func fullEndpoint() *endpoint.Endpoint {
+	return &endpoint.Endpoint{
+		DNSName:       "test.example.com",
+		Targets:       endpoint.Targets{"1.2.3.4", "5.6.7.8"},
+		RecordType:    endpoint.RecordTypeA,
+		SetIdentifier: "eu-west",
+		RecordTTL:     300,
+		Labels: endpoint.Labels{
+			endpoint.OwnerLabelKey:    "owner",
+			endpoint.ResourceLabelKey: "ingress/default/test",
+		},
+		ProviderSpecific: endpoint.ProviderSpecific{
+			{Name: "alias", Value: "false"},
+		},
+	}
+}
+
+func TestFieldNamingRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		naming   FieldNaming
+		contains []string
+	}{
+		{
+			naming:   "",
+			contains: []string{`"dnsName"`, `"recordType"`, `"setIdentifier"`, `"recordTTL"`, `"providerSpecific"`, `"Create"`, `"UpdateOld"`},
+		},
+		{
+			naming:   FieldNamingCamelCase,
+			contains: []string{`"dnsName"`, `"recordType"`, `"setIdentifier"`, `"recordTTL"`, `"providerSpecific"`, `"Create"`, `"UpdateOld"`},
+		},
+		{
+			naming:   FieldNamingSnakeCase,
+			contains: []string{`"dns_name"`, `"record_type"`, `"set_identifier"`, `"record_ttl"`, `"provider_specific"`, `"create"`, `"update_old"`},
+		},
+	} {
+		t.Run(string(tc.naming), func(t *testing.T) {
+			b := new(bytes.Buffer)
+			require.NoError(t, tc.naming.EncodeEndpoints(b, []*endpoint.Endpoint{fullEndpoint()}))
+			endpoints := []*endpoint.Endpoint{}
+			require.NoError(t, tc.naming.DecodeEndpoints(b, &endpoints))
+			require.Equal(t, []*endpoint.Endpoint{fullEndpoint()}, endpoints)
+
+			changes := &plan.Changes{
+				Create:    []*endpoint.Endpoint{fullEndpoint()},
+				UpdateOld: []*endpoint.Endpoint{fullEndpoint()},
+				UpdateNew: []*endpoint.Endpoint{fullEndpoint()},
+				Delete:    []*endpoint.Endpoint{fullEndpoint()},
+			}
+			b.Reset()
+			require.NoError(t, tc.naming.EncodeChanges(b, changes))
+			for _, c := range tc.contains {
+				require.Contains(t, b.String(), c)
+			}
+			decoded := plan.Changes{}
+			require.NoError(t, tc.naming.DecodeChanges(b, &decoded))
+			require.Equal(t, *changes, decoded)
+		})
+	}
+}
+
+func TestRecordsSnakeCase(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		w.Write([]byte(`[{
+			"dns_name": "test.example.com",
+			"record_type": "A",
+			"record_ttl": 60,
+			"targets": ["1.2.3.4"]
+		}]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithFieldNaming(FieldNamingSnakeCase))
+	require.NoError(t, err)
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []*endpoint.Endpoint{{
+		DNSName:    "test.example.com",
+		RecordType: endpoint.RecordTypeA,
+		RecordTTL:  60,
+		Targets:    endpoint.Targets{"1.2.3.4"},
+	}}, endpoints)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const adjustCacheKey = "adjustendpoints"
+
+// responseCache keeps the endpoints decoded from the last response of every kind of request,
+// along with the hash of the payload they were decoded from
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedEndpoints
+}
+
+type cachedEndpoints struct {
+	hash      [sha256.Size]byte
+	endpoints []*endpoint.Endpoint
+}
+
+// WebhookWithNoopFastPath skips the redundant work of reconciliations where nothing changed.
+// Records responses identical to the previous ones are not decoded again, and as long as the records
+// are unchanged, AdjustEndpoints returns the previous result without calling the webhook when it is
+// called with the same endpoints.
+func WebhookWithNoopFastPath() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.noopCache = &responseCache{entries: map[string]cachedEndpoints{}}
+	}
+}
+
+// lookup returns a copy of the endpoints cached for key if they were decoded from a payload with the given hash
+func (c *responseCache) lookup(key string, hash [sha256.Size]byte) ([]*endpoint.Endpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.entries[key]
+	if !ok || cached.hash != hash {
+		return nil, false
+	}
+	return copyEndpoints(cached.endpoints), true
+}
+
+func (c *responseCache) store(key string, hash [sha256.Size]byte, endpoints []*endpoint.Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedEndpoints{hash: hash, endpoints: copyEndpoints(endpoints)}
+}
+
+func (c *responseCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// decodeEndpointsCached decodes the endpoints of the response like decodeEndpoints,
+// reusing the endpoints cached for key when the response body is unchanged.
+// It returns whether the cached endpoints were used.
+func (p WebhookProvider) decodeEndpointsCached(key string, codec Codec, resp *http.Response, endpoints *[]*endpoint.Endpoint) (bool, error) {
+	if p.noopCache == nil {
+		return false, p.decodeEndpoints(codec, resp, endpoints)
+	}
+	b, err := p.readResponse(resp)
+	if err != nil {
+		return false, err
+	}
+
+	hash := sha256.Sum256(b)
+	if cached, ok := p.noopCache.lookup(key, hash); ok {
+		*endpoints = cached
+		return true, nil
+	}
+	if err := decodeBody(codec, resp, b, endpoints); err != nil {
+		return false, err
+	}
+	p.noopCache.store(key, hash, *endpoints)
+	return false, nil
+}
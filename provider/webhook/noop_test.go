@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// newStaticServer returns a webhook server returning the records set with setRecords
+// and echoing the endpoints sent to /adjustendpoints
+func newStaticServer(adjustCalls *int32) (*httptest.Server, func([]byte)) {
+	var mu sync.Mutex
+	records := []byte(`[]`)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/adjustendpoints":
+			atomic.AddInt32(adjustCalls, 1)
+			io.Copy(w, r.Body)
+		default:
+			mu.Lock()
+			defer mu.Unlock()
+			w.Write(records)
+		}
+	}))
+	return svr, func(b []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		records = b
+	}
+}
+
+func staticEndpoints(n int) []*endpoint.Endpoint {
+	endpoints := make([]*endpoint.Endpoint, 0, n)
+	for i := 0; i < n; i++ {
+		endpoints = append(endpoints, endpoint.NewEndpointWithTTL(fmt.Sprintf("record-%d.example.com", i), endpoint.RecordTypeA, 300, "1.2.3.4").WithProviderSpecific("weight", "10"))
+	}
+	return endpoints
+}
+
+func TestNoopFastPath(t *testing.T) {
+	var adjustCalls int32
+	svr, setRecords := newStaticServer(&adjustCalls)
+	defer svr.Close()
+	b, err := json.Marshal(staticEndpoints(3))
+	require.NoError(t, err)
+	setRecords(b)
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithNoopFastPath())
+	require.NoError(t, err)
+
+	reconcile := func(desired []*endpoint.Endpoint) []*endpoint.Endpoint {
+		records, err := provider.Records(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		// callers may modify the returned endpoints without affecting the next cycles
+		records[0].Targets = endpoint.Targets{"9.9.9.9"}
+		adjusted, err := provider.AdjustEndpoints(desired)
+		require.NoError(t, err)
+		return adjusted
+	}
+
+	unchanged := testutil.ToFloat64(recordsUnchangedCounter)
+	reconcile(staticEndpoints(2))
+	require.Equal(t, int32(1), atomic.LoadInt32(&adjustCalls))
+
+	// nothing changed: the webhook is not asked to adjust the same endpoints again
+	adjusted := reconcile(staticEndpoints(2))
+	require.Len(t, adjusted, 2)
+	require.Equal(t, int32(1), atomic.LoadInt32(&adjustCalls))
+	require.Equal(t, unchanged+1, testutil.ToFloat64(recordsUnchangedCounter))
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, endpoint.Targets{"1.2.3.4"}, records[0].Targets)
+
+	// changed desired endpoints break the short-circuit
+	reconcile(staticEndpoints(3))
+	require.Equal(t, int32(2), atomic.LoadInt32(&adjustCalls))
+
+	// changed records break the short-circuit
+	changed := staticEndpoints(3)
+	changed[2].Targets = endpoint.Targets{"5.6.7.8"}
+	b, err = json.Marshal(changed)
+	require.NoError(t, err)
+	setRecords(b)
+	unchanged = testutil.ToFloat64(recordsUnchangedCounter)
+	reconcile(staticEndpoints(3))
+	require.Equal(t, int32(3), atomic.LoadInt32(&adjustCalls))
+	require.Equal(t, unchanged, testutil.ToFloat64(recordsUnchangedCounter))
+}
+
+func benchmarkReconcile(b *testing.B, opts ...WebhookOption) {
+	var adjustCalls int32
+	svr, setRecords := newStaticServer(&adjustCalls)
+	defer svr.Close()
+	endpoints := staticEndpoints(5000)
+	records, err := json.Marshal(endpoints)
+	require.NoError(b, err)
+	setRecords(records)
+
+	provider, err := NewWebhookProvider(svr.URL, opts...)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := provider.Records(context.TODO()); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := provider.AdjustEndpoints(endpoints); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStaticReconcile(b *testing.B) {
+	benchmarkReconcile(b)
+}
+
+func BenchmarkStaticReconcileNoopFastPath(b *testing.B) {
+	benchmarkReconcile(b, WebhookWithNoopFastPath())
+}
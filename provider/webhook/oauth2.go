@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ErrTokenEndpoint is returned when no token could be obtained from the OAuth2 token endpoint,
+// as opposed to errors returned by the webhook itself
+var ErrTokenEndpoint = errors.New("oauth2 token endpoint error")
+
+// OAuth2Authenticator authenticates the requests with a bearer token obtained with the OAuth2
+// client credentials grant. The token is cached and renewed shortly before it expires.
+type OAuth2Authenticator struct {
+	config *clientcredentials.Config
+	mu     sync.Mutex
+	source oauth2.TokenSource
+}
+
+// NewOAuth2Authenticator returns an OAuth2Authenticator obtaining its tokens from tokenURL
+func NewOAuth2Authenticator(clientID, clientSecret, tokenURL string, scopes []string) *OAuth2Authenticator {
+	a := &OAuth2Authenticator{
+		config: &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       scopes,
+		},
+	}
+	a.source = a.newTokenSource()
+	return a
+}
+
+// WebhookWithOAuth2ClientCredentials authenticates the requests with a bearer token obtained
+// from tokenURL with the OAuth2 client credentials grant
+func WebhookWithOAuth2ClientCredentials(clientID, clientSecret, tokenURL string, scopes []string) WebhookOption {
+	return WebhookWithAuthenticator(NewOAuth2Authenticator(clientID, clientSecret, tokenURL, scopes))
+}
+
+func (a *OAuth2Authenticator) newTokenSource() oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, a.config.TokenSource(context.Background()))
+}
+
+// Authenticate sets the current bearer token on req, fetching a new one if it expired
+func (a *OAuth2Authenticator) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	source := a.source
+	a.mu.Unlock()
+
+	token, err := source.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTokenEndpoint, err)
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+// Refresh drops the cached token, so that a new one is fetched by the next request
+func (a *OAuth2Authenticator) Refresh(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.source = a.newTokenSource()
+	return nil
+}
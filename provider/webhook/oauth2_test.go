@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenEndpoint returns an OAuth2 token endpoint issuing token-1, token-2, ... valid for expiresIn seconds
+func newTokenEndpoint(t *testing.T, expiresIn int, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "client" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		expiresIn int
+		issued    int32
+	}{
+		// tokens are renewed 10 seconds before they expire
+		{name: "cached token", expiresIn: 3600, issued: 1},
+		{name: "expired token", expiresIn: 1, issued: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var issued, calls int32
+			tokens := newTokenEndpoint(t, tc.expiresIn, &issued)
+			defer tokens.Close()
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Regexp(t, "^Bearer token-[0-9]+$", r.Header.Get("Authorization"))
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				atomic.AddInt32(&calls, 1)
+				w.Write([]byte(`[]`))
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithOAuth2ClientCredentials("client", "secret", tokens.URL, []string{"dns"}))
+			require.NoError(t, err)
+			for i := 0; i < 2; i++ {
+				_, err = provider.Records(context.TODO())
+				require.NoError(t, err)
+			}
+			require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+			require.Equal(t, tc.issued, atomic.LoadInt32(&issued))
+		})
+	}
+}
+
+func TestOAuth2TokenEndpointFailure(t *testing.T) {
+	var issued int32
+	tokens := newTokenEndpoint(t, 3600, &issued)
+	defer tokens.Close()
+	svr := newPayloadServer(`[]`)
+	defer svr.Close()
+
+	_, err := NewWebhookProvider(svr.URL, WebhookWithOAuth2ClientCredentials("client", "wrong", tokens.URL, nil))
+	require.ErrorIs(t, err, ErrTokenEndpoint)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ApplyOperation is a kind of change sent by ApplyChanges
+type ApplyOperation string
+
+const (
+	ApplyOperationCreate ApplyOperation = "create"
+	ApplyOperationUpdate ApplyOperation = "update"
+	ApplyOperationDelete ApplyOperation = "delete"
+)
+
+// ParseApplyOrder parses a comma separated list of operations, e.g. "create,update,delete"
+func ParseApplyOrder(s string) ([]ApplyOperation, error) {
+	var order []ApplyOperation
+	seen := map[ApplyOperation]bool{}
+	for _, part := range strings.Split(s, ",") {
+		op := ApplyOperation(strings.ToLower(strings.TrimSpace(part)))
+		switch op {
+		case ApplyOperationCreate, ApplyOperationUpdate, ApplyOperationDelete:
+		default:
+			return nil, fmt.Errorf("unknown apply operation %q", part)
+		}
+		if seen[op] {
+			return nil, fmt.Errorf("duplicate apply operation %q", part)
+		}
+		seen[op] = true
+		order = append(order, op)
+	}
+	return order, nil
+}
+
+// WebhookWithApplyOrder sends the changes of each operation with a separate request, in the given order,
+// for webhooks that need e.g. the deletions to be applied last. Operations missing from order are sent after
+// the others, in the default order create, update, delete.
+// Without order, all the changes are sent with a single request.
+func WebhookWithApplyOrder(order ...ApplyOperation) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.applyOrder = order
+	}
+}
+
+// applyChanges sends the changes to the webhook, with one request per operation if an apply order is configured,
+// split into batches if batching is configured
+func (p WebhookProvider) applyChanges(ctx context.Context, changes *plan.Changes, extraHeaders map[string]string) error {
+	post := p.postChanges
+	if p.errorIsolation && !p.replacesRecords() {
+		post = p.postChangesIsolated
+	}
+	if p.batching != nil && !p.replacesRecords() {
+		post = p.batched(post)
+	}
+	if len(p.applyOrder) == 0 || changes == nil || p.replacesRecords() {
+		return post(ctx, changes, extraHeaders)
+	}
+	for _, op := range completeApplyOrder(p.applyOrder) {
+		part := changesOf(changes, op)
+		if !part.HasChanges() {
+			continue
+		}
+		if err := post(ctx, part, extraHeaders); err != nil {
+			return fmt.Errorf("failed to apply %s changes: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// completeApplyOrder returns order followed by the operations missing from it, so that no change is left unsent
+func completeApplyOrder(order []ApplyOperation) []ApplyOperation {
+	complete := append([]ApplyOperation{}, order...)
+	for _, op := range []ApplyOperation{ApplyOperationCreate, ApplyOperationUpdate, ApplyOperationDelete} {
+		missing := true
+		for _, o := range order {
+			if o == op {
+				missing = false
+			}
+		}
+		if missing {
+			complete = append(complete, op)
+		}
+	}
+	return complete
+}
+
+// changesOf returns the changes of a single operation
+func changesOf(changes *plan.Changes, op ApplyOperation) *plan.Changes {
+	switch op {
+	case ApplyOperationCreate:
+		return &plan.Changes{Create: changes.Create}
+	case ApplyOperationUpdate:
+		return &plan.Changes{UpdateOld: changes.UpdateOld, UpdateNew: changes.UpdateNew}
+	case ApplyOperationDelete:
+		return &plan.Changes{Delete: changes.Delete}
+	}
+	return &plan.Changes{}
+}
+
+// operationsOf returns the operations contained in the changes, e.g. "create+delete", or "none"
+func operationsOf(changes *plan.Changes) string {
+	if changes == nil {
+		return "none"
+	}
+	var ops []string
+	if len(changes.Create) > 0 {
+		ops = append(ops, string(ApplyOperationCreate))
+	}
+	if len(changes.UpdateNew) > 0 {
+		ops = append(ops, string(ApplyOperationUpdate))
+	}
+	if len(changes.Delete) > 0 {
+		ops = append(ops, string(ApplyOperationDelete))
+	}
+	if len(ops) == 0 {
+		return "none"
+	}
+	return strings.Join(ops, "+")
+}
+
+// WebhookWithPayloadOrder orders the operations within the payload of a single ApplyChanges request:
+// the endpoints of every operation are sorted by DNS name and the operations are serialized in the given order,
+// so that a webhook processing the payload sequentially handles the operations on the same name in that order,
+// e.g. deleting a record before creating its replacement. Operations missing from order are serialized last.
+// Unlike WebhookWithApplyOrder, all the changes are still sent with one request.
+func WebhookWithPayloadOrder(order ...ApplyOperation) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.payloadOrder = order
+	}
+}
+
+// sortedChanges returns a copy of changes with the endpoints of every operation sorted by DNS name,
+// keeping the update pairs aligned
+func sortedChanges(changes *plan.Changes) *plan.Changes {
+	if changes == nil {
+		return nil
+	}
+	sorted := &plan.Changes{
+		Create: sortedByName(changes.Create),
+		Delete: sortedByName(changes.Delete),
+	}
+	if len(changes.UpdateOld) != len(changes.UpdateNew) {
+		sorted.UpdateOld, sorted.UpdateNew = changes.UpdateOld, changes.UpdateNew
+		return sorted
+	}
+	indexes := make([]int, len(changes.UpdateNew))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return normalizeName(changes.UpdateNew[indexes[i]].DNSName) < normalizeName(changes.UpdateNew[indexes[j]].DNSName)
+	})
+	for _, i := range indexes {
+		sorted.UpdateOld = append(sorted.UpdateOld, changes.UpdateOld[i])
+		sorted.UpdateNew = append(sorted.UpdateNew, changes.UpdateNew[i])
+	}
+	return sorted
+}
+
+func sortedByName(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+	sorted := append([]*endpoint.Endpoint{}, endpoints...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return normalizeName(sorted[i].DNSName) < normalizeName(sorted[j].DNSName)
+	})
+	return sorted
+}
+
+// operationOfKey returns the operation of a key of the serialized changes, whatever the field naming
+func operationOfKey(key string) (ApplyOperation, bool) {
+	switch strings.ReplaceAll(strings.ToLower(key), "_", "") {
+	case "create":
+		return ApplyOperationCreate, true
+	case "updateold", "updatenew":
+		return ApplyOperationUpdate, true
+	case "delete":
+		return ApplyOperationDelete, true
+	}
+	return "", false
+}
+
+// orderPayload rewrites the JSON encoded changes with their keys in the order of the operations
+func orderPayload(payload []byte, order []ApplyOperation) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return payload, nil
+	}
+	rank := func(key string) int {
+		op, ok := operationOfKey(key)
+		for i, o := range order {
+			if ok && o == op {
+				// the old endpoints of the updates precede the new ones
+				if strings.HasSuffix(strings.ToLower(key), "new") {
+					return 2*i + 1
+				}
+				return 2 * i
+			}
+		}
+		return 2 * len(order)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	// unknown keys keep a stable order
+	sort.Strings(keys)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return rank(keys[i]) < rank(keys[j])
+	})
+
+	b := new(bytes.Buffer)
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(fields[key])
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
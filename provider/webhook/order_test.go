@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestParseApplyOrder(t *testing.T) {
+	order, err := ParseApplyOrder("delete, Create,update")
+	require.NoError(t, err)
+	require.Equal(t, []ApplyOperation{ApplyOperationDelete, ApplyOperationCreate, ApplyOperationUpdate}, order)
+
+	_, err = ParseApplyOrder("create,upsert")
+	require.EqualError(t, err, `unknown apply operation "upsert"`)
+	_, err = ParseApplyOrder("create,create")
+	require.EqualError(t, err, `duplicate apply operation "create"`)
+}
+
+func TestApplyOrder(t *testing.T) {
+	var requests []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		var changes plan.Changes
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		switch {
+		case len(changes.Create) > 0:
+			requests = append(requests, "create")
+		case len(changes.UpdateNew) > 0:
+			require.Len(t, changes.UpdateOld, 1)
+			requests = append(requests, "update")
+		case len(changes.Delete) > 0:
+			requests = append(requests, "delete")
+		}
+		require.Equal(t, 1, len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA}},
+		UpdateOld: []*endpoint.Endpoint{{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}}},
+		UpdateNew: []*endpoint.Endpoint{{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"2.2.2.2"}}},
+		Delete:    []*endpoint.Endpoint{{DNSName: "c.example.com", RecordType: endpoint.RecordTypeA}},
+	}
+	for _, tc := range []struct {
+		order    string
+		requests []string
+	}{
+		{order: "create,update,delete", requests: []string{"create", "update", "delete"}},
+		{order: "delete,create,update", requests: []string{"delete", "create", "update"}},
+		// the deletions missing from the order are sent last
+		{order: "update,create", requests: []string{"update", "create", "delete"}},
+		{order: "delete", requests: []string{"delete", "create", "update"}},
+	} {
+		t.Run(tc.order, func(t *testing.T) {
+			requests = nil
+			order, err := ParseApplyOrder(tc.order)
+			require.NoError(t, err)
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithApplyOrder(order...))
+			require.NoError(t, err)
+
+			require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+			require.Equal(t, tc.requests, requests)
+		})
+	}
+}
+
+func TestPayloadOrder(t *testing.T) {
+	var payload string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		payload = string(b)
+		var changes plan.Changes
+		require.NoError(t, json.Unmarshal(b, &changes))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPayloadOrder(ApplyOperationDelete, ApplyOperationCreate, ApplyOperationUpdate))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("bar.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeTXT, "old"),
+			endpoint.NewEndpoint("bar.example.com", endpoint.RecordTypeTXT, "old"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeTXT, "new-foo"),
+			endpoint.NewEndpoint("bar.example.com", endpoint.RecordTypeTXT, "new-bar"),
+		},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeCNAME, "old.example.com")},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	// the operations on foo.example.com come in the configured order
+	deleteFoo := strings.Index(payload, `"old.example.com"`)
+	createFoo := strings.Index(payload, `"dnsName":"foo.example.com","targets":["1.2.3.4"]`)
+	updateFoo := strings.Index(payload, `"new-foo"`)
+	require.Positive(t, deleteFoo)
+	require.Greater(t, createFoo, deleteFoo)
+	require.Greater(t, updateFoo, createFoo)
+	// the endpoints of an operation are sorted by name, and the update pairs stay aligned
+	require.Less(t, strings.Index(payload, `"dnsName":"bar.example.com","targets":["1.2.3.4"]`), createFoo)
+	require.Less(t, strings.Index(payload, `"new-bar"`), updateFoo)
+	require.Less(t, strings.Index(payload, `"UpdateOld"`), strings.Index(payload, `"UpdateNew"`))
+	// the plan is left untouched
+	require.Equal(t, "foo.example.com", changes.Create[0].DNSName)
+}
+
+func TestOrderPayloadSnakeCase(t *testing.T) {
+	ordered, err := orderPayload([]byte(`{"create":[1],"update_old":[2],"update_new":[3],"delete":[4]}`), []ApplyOperation{ApplyOperationUpdate, ApplyOperationDelete})
+	require.NoError(t, err)
+	require.Equal(t, `{"update_old":[2],"update_new":[3],"delete":[4],"create":[1]}`+"\n", string(ordered))
+}
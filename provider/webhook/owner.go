@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/url"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ownerQueryParameter is the query parameter of GET /records holding the owner of the records to return
+const ownerQueryParameter = "owner"
+
+// WebhookWithOwnerFilter only returns from Records the records owned by ownerID, as found in their owner label.
+// Webhooks advertising the ownerFilter capability receive the owner with GET /records, so that they only return
+// its records, the records of the other webhooks are filtered by external-dns.
+//
+// This isolates the instances of external-dns sharing a webhook: ApplyChanges sets the owner label of
+// the created and updated endpoints to ownerID, and never deletes or updates the records of other owners,
+// nor creates records returned by Records for other owners.
+//
+// The owner labels must be stored by the webhook, e.g. with the noop registry. The TXT records without
+// owner label are kept, updated and deleted though, so that the TXT registry can read and manage its
+// ownership records, which carry the owners in their targets.
+func WebhookWithOwnerFilter(ownerID string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.ownerFilter = ownerID
+		p.foreignRecords = &foreignRecords{}
+		p.endpointTransforms = append(p.endpointTransforms, func(e *endpoint.Endpoint) {
+			if e.Labels == nil {
+				e.Labels = endpoint.NewLabels()
+			}
+			if e.Labels[endpoint.OwnerLabelKey] == "" {
+				e.Labels[endpoint.OwnerLabelKey] = ownerID
+			}
+		})
+	}
+}
+
+// foreignRecords holds the owners of the records of other owners returned by the last Records call
+type foreignRecords struct {
+	mu     sync.Mutex
+	owners map[endpoint.EndpointKey]string
+}
+
+func (f *foreignRecords) reset() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.owners = map[endpoint.EndpointKey]string{}
+}
+
+func (f *foreignRecords) add(e *endpoint.Endpoint) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.owners == nil {
+		f.owners = map[endpoint.EndpointKey]string{}
+	}
+	f.owners[e.Key()] = e.Labels[endpoint.OwnerLabelKey]
+}
+
+func (f *foreignRecords) owner(e *endpoint.Endpoint) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	owner, ok := f.owners[e.Key()]
+	return owner, ok
+}
+
+// recordsQuery encodes the query of GET /records, adding the owner when the webhook filters the records by owner
+func (p WebhookProvider) recordsQuery(query url.Values) string {
+	if p.ownerFilter != "" && p.capabilities.OwnerFilter {
+		query.Set(ownerQueryParameter, p.ownerFilter)
+	}
+	return query.Encode()
+}
+
+func (p WebhookProvider) isOwned(e *endpoint.Endpoint) bool {
+	return e.IsOwnedBy(p.ownerFilter)
+}
+
+// isOwnershipRecord returns true if e may be an ownership record of the TXT registry, whose owner is in its targets
+func isOwnershipRecord(e *endpoint.Endpoint) bool {
+	return e.RecordType == endpoint.RecordTypeTXT && e.Labels[endpoint.OwnerLabelKey] == ""
+}
+
+// filterOwned returns the endpoints owned by the owner filter and the TXT ownership records, keeping track of the records of other owners
+func (p WebhookProvider) filterOwned(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	owned := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if p.isOwned(e) || isOwnershipRecord(e) {
+			owned = append(owned, e)
+			continue
+		}
+		if e.Labels[endpoint.OwnerLabelKey] != "" {
+			p.foreignRecords.add(e)
+		}
+	}
+	return owned
+}
+
+// ownedChanges returns changes without the changes to the records of other owners
+func (p WebhookProvider) ownedChanges(changes *plan.Changes) *plan.Changes {
+	owned := &plan.Changes{}
+	for _, e := range changes.Create {
+		if err := p.checkNotForeign(e); err != "" {
+			logSkippedForeign("create", e, err)
+			continue
+		}
+		owned.Create = append(owned.Create, e)
+	}
+	for i, e := range changes.UpdateNew {
+		if i >= len(changes.UpdateOld) {
+			break
+		}
+		old := changes.UpdateOld[i]
+		if !p.isOwned(old) && !isOwnershipRecord(old) {
+			logSkippedForeign("update", old, "owned by "+ownerOf(old))
+			continue
+		}
+		if err := p.checkNotForeign(e); err != "" {
+			logSkippedForeign("update", e, err)
+			continue
+		}
+		owned.UpdateOld = append(owned.UpdateOld, old)
+		owned.UpdateNew = append(owned.UpdateNew, e)
+	}
+	for _, e := range changes.Delete {
+		if !p.isOwned(e) && !isOwnershipRecord(e) {
+			logSkippedForeign("delete", e, "owned by "+ownerOf(e))
+			continue
+		}
+		owned.Delete = append(owned.Delete, e)
+	}
+	return owned
+}
+
+// checkNotForeign returns why e must not be created or updated by this owner, if it must not
+func (p WebhookProvider) checkNotForeign(e *endpoint.Endpoint) string {
+	if owner := e.Labels[endpoint.OwnerLabelKey]; owner != "" && owner != p.ownerFilter {
+		return "labeled with owner " + owner
+	}
+	if owner, ok := p.foreignRecords.owner(e); ok {
+		return "record owned by " + owner
+	}
+	return ""
+}
+
+func ownerOf(e *endpoint.Endpoint) string {
+	if owner := e.Labels[endpoint.OwnerLabelKey]; owner != "" {
+		return owner
+	}
+	return "nobody"
+}
+
+func logSkippedForeign(op string, e *endpoint.Endpoint, reason string) {
+	log.Warnf("Skipping %s of %s %s not owned by this external-dns: %s", op, e.RecordType, e.DNSName, reason)
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestOwnerFilter(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		negotiation string
+		owner       string
+		records     string
+	}{
+		{
+			name:        "webhook filtering by owner",
+			negotiation: `{"capabilities": {"ownerFilter": true}}`,
+			owner:       "default",
+			records:     `[{"dnsName":"owned.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"default"}}]`,
+		},
+		{
+			name:        "filtering by external-dns",
+			negotiation: `{}`,
+			records: `[
+				{"dnsName":"owned.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"default"}},
+				{"dnsName":"other.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"other"}},
+				{"dnsName":"unowned.example.com","recordType":"A","targets":["1.2.3.4"]}
+			]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			owner := "unset"
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(tc.negotiation))
+					return
+				}
+				owner = r.URL.Query().Get(ownerQueryParameter)
+				w.Write([]byte(tc.records))
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter("default"))
+			require.NoError(t, err)
+
+			endpoints, err := provider.Records(context.TODO())
+			require.NoError(t, err)
+			require.Equal(t, tc.owner, owner)
+			require.Len(t, endpoints, 1)
+			require.Equal(t, "owned.example.com", endpoints[0].DNSName)
+		})
+	}
+}
+
+// sharedWebhook is a webhook storing the records of several external-dns instances, labels included.
+// Like a naive backend, it overwrites the existing records on create.
+type sharedWebhook struct {
+	mu          sync.Mutex
+	records     map[endpoint.EndpointKey]*endpoint.Endpoint
+	ownerFilter bool
+}
+
+func newSharedWebhook(t *testing.T, ownerFilter bool) (*sharedWebhook, *httptest.Server) {
+	w := &sharedWebhook{records: map[endpoint.EndpointKey]*endpoint.Endpoint{}, ownerFilter: ownerFilter}
+	svr := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if r.URL.Path == "/" {
+			rw.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			json.NewEncoder(rw).Encode(negotiationResponse{Capabilities: capabilities{OwnerFilter: ownerFilter}})
+			return
+		}
+		if r.Method == http.MethodGet {
+			owner := r.URL.Query().Get(ownerQueryParameter)
+			records := []*endpoint.Endpoint{}
+			for _, e := range w.records {
+				if !ownerFilter || e.IsOwnedBy(owner) {
+					records = append(records, e)
+				}
+			}
+			rw.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			json.NewEncoder(rw).Encode(records)
+			return
+		}
+		if r.URL.Path == "/adjustendpoints" {
+			rw.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			io.Copy(rw, r.Body)
+			return
+		}
+		var changes plan.Changes
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		for _, e := range changes.Delete {
+			delete(w.records, e.Key())
+		}
+		for _, e := range changes.UpdateOld {
+			delete(w.records, e.Key())
+		}
+		for _, e := range append(changes.Create, changes.UpdateNew...) {
+			w.records[e.Key()] = e
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	return w, svr
+}
+
+// state returns the DNS names of the records with their owner and targets
+func (w *sharedWebhook) state() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var state []string
+	for _, e := range w.records {
+		state = append(state, fmt.Sprintf("%s %s %s", e.DNSName, e.Labels[endpoint.OwnerLabelKey], e.Targets))
+	}
+	sort.Strings(state)
+	return state
+}
+
+// reconcile runs a reconciliation of owner towards desired, returning whether changes were applied
+func reconcile(t *testing.T, provider *WebhookProvider, owner string, desired ...*endpoint.Endpoint) bool {
+	current, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	// the controller plans the adjusted endpoints
+	desired, err = provider.AdjustEndpoints(desired)
+	require.NoError(t, err)
+	changes := (&plan.Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        owner,
+	}).Calculate().Changes
+	if !changes.HasChanges() {
+		return false
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	return true
+}
+
+func TestOwnerIsolation(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		ownerFilter bool
+	}{
+		{name: "filtering by external-dns"},
+		{name: "webhook filtering by owner", ownerFilter: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook, svr := newSharedWebhook(t, tc.ownerFilter)
+			defer svr.Close()
+
+			const owners = 5
+			providers := make([]*WebhookProvider, owners)
+			desired := make([][]*endpoint.Endpoint, owners)
+			var expected []string
+			for i := range providers {
+				owner := fmt.Sprintf("owner-%d", i)
+				provider, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter(owner))
+				require.NoError(t, err)
+				providers[i] = provider
+				for _, name := range []string{"a", "b"} {
+					dnsName := fmt.Sprintf("%s.%s.example.com", name, owner)
+					desired[i] = append(desired[i], endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, "1.2.3.4"))
+					expected = append(expected, dnsName+" "+owner+" 1.2.3.4")
+				}
+			}
+			owner := func(i int) string {
+				return fmt.Sprintf("owner-%d", i)
+			}
+
+			// every owner creates its records, labeled with its owner
+			for i, provider := range providers {
+				require.True(t, reconcile(t, provider, owner(i), desired[i]...))
+			}
+			sort.Strings(expected)
+			require.Equal(t, expected, webhook.state())
+
+			// the records of the other owners are neither returned nor changed
+			for i, provider := range providers {
+				records, err := provider.Records(context.TODO())
+				require.NoError(t, err)
+				require.Len(t, records, 2)
+				for _, r := range records {
+					require.Equal(t, owner(i), r.Labels[endpoint.OwnerLabelKey])
+				}
+				require.False(t, reconcile(t, provider, owner(i), desired[i]...))
+			}
+			require.Equal(t, expected, webhook.state())
+
+			// an owner updating its records only updates its own records
+			updated := []*endpoint.Endpoint{
+				endpoint.NewEndpoint("a.owner-1.example.com", endpoint.RecordTypeA, "4.3.2.1"),
+				endpoint.NewEndpoint("b.owner-1.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			}
+			require.True(t, reconcile(t, providers[1], owner(1), updated...))
+			for i := range expected {
+				if expected[i] == "a.owner-1.example.com owner-1 1.2.3.4" {
+					expected[i] = "a.owner-1.example.com owner-1 4.3.2.1"
+				}
+			}
+			require.Equal(t, expected, webhook.state())
+
+			// an owner removing all its records only deletes its own records
+			require.True(t, reconcile(t, providers[2], owner(2)))
+			var remaining []string
+			for _, r := range expected {
+				if !strings.Contains(r, " owner-2 ") {
+					remaining = append(remaining, r)
+				}
+			}
+			require.Equal(t, remaining, webhook.state())
+
+			// the others are unaffected on their next reconciliation
+			for _, i := range []int{0, 3, 4} {
+				require.False(t, reconcile(t, providers[i], owner(i), desired[i]...))
+			}
+			require.Equal(t, remaining, webhook.state())
+
+			// changes to the records of other owners are skipped even when planned
+			foreign := endpoint.NewEndpoint("a.owner-4.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			foreign.Labels[endpoint.OwnerLabelKey] = owner(4)
+			replaced := endpoint.NewEndpoint("a.owner-4.example.com", endpoint.RecordTypeA, "6.6.6.6")
+			replaced.Labels[endpoint.OwnerLabelKey] = owner(4)
+			require.NoError(t, providers[3].ApplyChanges(context.TODO(), &plan.Changes{
+				Create:    []*endpoint.Endpoint{replaced},
+				UpdateOld: []*endpoint.Endpoint{foreign},
+				UpdateNew: []*endpoint.Endpoint{replaced},
+				Delete:    []*endpoint.Endpoint{foreign, endpoint.NewEndpoint("b.owner-4.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			}))
+			require.Equal(t, remaining, webhook.state())
+		})
+	}
+}
+
+func TestOwnerIsolationCreate(t *testing.T) {
+	// a record of another owner can't be overwritten by a create when external-dns filters the records
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+
+	first, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter("first"))
+	require.NoError(t, err)
+	second, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter("second"))
+	require.NoError(t, err)
+
+	require.True(t, reconcile(t, first, "first", endpoint.NewEndpoint("shared.example.com", endpoint.RecordTypeA, "1.1.1.1")))
+	require.True(t, reconcile(t, second, "second", endpoint.NewEndpoint("shared.example.com", endpoint.RecordTypeA, "2.2.2.2")))
+	require.Equal(t, []string{"shared.example.com first 1.1.1.1"}, webhook.state())
+}
+
+func TestOwnerFilterTXTRegistry(t *testing.T) {
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+	// the ownership records of the TXT registry carry the owner in their targets rather than in a label
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	a.Labels[endpoint.OwnerLabelKey] = "default"
+	txt := endpoint.NewEndpoint("a-a.example.com", endpoint.RecordTypeTXT, `"heritage=external-dns,external-dns/owner=default"`)
+	webhook.records[a.Key()] = a
+	webhook.records[txt.Key()] = txt
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter("default"))
+	require.NoError(t, err)
+	r, err := registry.NewTXTRegistry(provider, "", "", "default", 0, "", []string{endpoint.RecordTypeA}, nil, false, nil)
+	require.NoError(t, err)
+
+	current, err := r.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, current, 1)
+	require.Equal(t, "default", current[0].Labels[endpoint.OwnerLabelKey])
+
+	// deleting the record deletes its ownership record too
+	changes := (&plan.Plan{
+		Current:        current,
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+		OwnerID:        "default",
+	}).Calculate().Changes
+	require.Len(t, changes.Delete, 1)
+	require.NoError(t, r.ApplyChanges(context.TODO(), changes))
+	require.Empty(t, webhook.state())
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ErrPreviewRejected is returned by ApplyChanges when the webhook refuses the preview of the changes
+var ErrPreviewRejected = errors.New("webhook rejected the preview of the changes")
+
+// WebhookWithPreview makes ApplyChanges send the changes to /records/preview before applying them,
+// so that the webhook can validate them without applying them. The preview carries the same payload as the request
+// applying the changes, e.g. the full record set when the records are replaced.
+// The changes are only applied if the preview succeeds, with a 200 or 204 response.
+func WebhookWithPreview() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.preview = true
+	}
+}
+
+// previewChanges sends the payload that applies the changes to the preview endpoint of the webhook
+func (p WebhookProvider) previewChanges(ctx context.Context, changes *plan.Changes) error {
+	u := p.remoteServerURL.JoinPath("records", "preview").String()
+
+	payload, _, err := p.applyPayload(changes)
+	if err != nil {
+		return err
+	}
+	body, encoding, err := p.compression.compress(payload)
+	if err != nil {
+		log.Debugf("Failed to compress changes: %s", err.Error())
+		return err
+	}
+	resp, err := p.doWithRetry(ctx, RetryOperationRecords, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		p.signer.signRequest(req, body)
+		req.Header.Set(contentTypeHeader, p.contentType())
+		req.Header.Set(acceptHeader, p.accept())
+		if encoding != "" {
+			req.Header.Set(contentEncodingHeader, encoding)
+		}
+		return req, nil
+	})
+	if err != nil {
+		log.Debugf("Failed to preview changes: %s", err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%w: code %d", ErrPreviewRejected, resp.StatusCode)
+	}
+	log.WithField("path", resp.Request.URL.Path).Debug("Previewed changes")
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestPreview(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		previewStatus int
+		applied       bool
+	}{
+		{name: "preview passes", previewStatus: http.StatusOK, applied: true},
+		{name: "preview fails", previewStatus: http.StatusUnprocessableEntity, applied: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				requests = append(requests, r.URL.Path)
+				var changes plan.Changes
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+				require.Len(t, changes.Create, 1)
+				if r.URL.Path == "/records/preview" {
+					w.WriteHeader(tc.previewStatus)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithPreview())
+			require.NoError(t, err)
+
+			err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			})
+			if tc.applied {
+				require.NoError(t, err)
+				require.Equal(t, []string{"/records/preview", "/records"}, requests)
+			} else {
+				require.ErrorIs(t, err, ErrPreviewRejected)
+				require.EqualError(t, err, "webhook rejected the preview of the changes: code 422")
+				require.Equal(t, []string{"/records/preview"}, requests)
+			}
+		})
+	}
+}
+
+func TestPreviewPayload(t *testing.T) {
+	bodies := map[string]string{}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.URL.Path == "/records/preview" {
+			require.Equal(t, mediaTypeFormatAndVersion, r.Header.Get(acceptHeader))
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies[r.URL.Path] = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPreview(), WebhookWithEnvelope(Envelope{ControllerID: "default"}), WebhookWithClock(newFakeClock()))
+	require.NoError(t, err)
+
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	require.Contains(t, bodies["/records/preview"], `"controllerId":"default"`)
+	require.Equal(t, bodies["/records"], bodies["/records/preview"])
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	changeIDHeader = "X-Change-Id"
+	// changeStatusInSync is the status of a change that has been propagated, as named by Route53
+	changeStatusInSync = "INSYNC"
+)
+
+type changeStatus struct {
+	Status string `json:"status"`
+}
+
+// propagationWait configures how the provider waits for changes to be propagated
+type propagationWait struct {
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// WebhookWithPropagationWait waits for the changes to be propagated before ApplyChanges returns.
+// When the webhook returns a change ID in the X-Change-Id header of the apply response,
+// GET /status/{id} is polled every interval until it reports the INSYNC status,
+// failing ApplyChanges if the change is not in sync within timeout. A zero timeout waits as long as
+// the context of ApplyChanges allows, the interval must be positive.
+func WebhookWithPropagationWait(interval, timeout time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.propagation = &propagationWait{interval: interval, timeout: timeout}
+	}
+}
+
+// validate rejects the intervals and timeouts the changes can't be polled with
+func (w *propagationWait) validate() error {
+	if w == nil {
+		return nil
+	}
+	if w.interval <= 0 {
+		return fmt.Errorf("propagation wait interval must be positive, got %s", w.interval)
+	}
+	if w.timeout < 0 {
+		return fmt.Errorf("propagation wait timeout must not be negative, got %s", w.timeout)
+	}
+	return nil
+}
+
+func (p WebhookProvider) waitForPropagation(ctx context.Context, changeID string) error {
+	if p.propagation.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.propagation.timeout)
+		defer cancel()
+	}
+
+	for {
+		status, err := p.changeStatus(ctx, changeID)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("change %s not in sync within %s: %w", changeID, p.propagation.timeout, ctx.Err())
+		}
+		if err != nil {
+			return err
+		}
+		if status == changeStatusInSync {
+			return nil
+		}
+		log.WithFields(log.Fields{"change": changeID, "status": status}).Debug("Waiting for change to propagate")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("change %s not in sync within %s: %w", changeID, p.propagation.timeout, ctx.Err())
+		case <-p.clockOrReal().After(p.propagation.interval):
+		}
+	}
+}
+
+func (p WebhookProvider) changeStatus(ctx context.Context, changeID string) (string, error) {
+	u := p.remoteServerURL.JoinPath("status", changeID).String()
+	resp, err := p.doWithRetry(ctx, RetryOperationRecords, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.signer.signRequest(req, nil)
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get status of change %s: %w", changeID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get status of change %s with code %d", changeID, resp.StatusCode)
+	}
+
+	status := changeStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", fmt.Errorf("failed to decode status of change %s: %w", changeID, err)
+	}
+	return status.Status, nil
+}
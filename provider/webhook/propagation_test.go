@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// newPropagatingServer returns a webhook server whose changes are in sync after the given number of polls
+func newPropagatingServer(t *testing.T, pending int32, polls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/records":
+			w.Header().Set(changeIDHeader, "c-1")
+			w.WriteHeader(http.StatusNoContent)
+		case "/status/c-1":
+			if atomic.AddInt32(polls, 1) <= pending {
+				w.Write([]byte(`{"status":"PENDING"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"INSYNC"}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestPropagationWait(t *testing.T) {
+	var polls int32
+	svr := newPropagatingServer(t, 3, &polls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPropagationWait(time.Millisecond, time.Second))
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Equal(t, int32(4), atomic.LoadInt32(&polls))
+}
+
+func TestPropagationWaitTimeout(t *testing.T) {
+	var polls int32
+	svr := newPropagatingServer(t, 1000, &polls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPropagationWait(time.Millisecond, 20*time.Millisecond))
+	require.NoError(t, err)
+	require.ErrorContains(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}), "change c-1 not in sync within 20ms")
+}
+
+func TestPropagationWaitDisabled(t *testing.T) {
+	var polls int32
+	svr := newPropagatingServer(t, 3, &polls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Zero(t, atomic.LoadInt32(&polls))
+}
+
+func TestPropagationWaitWithoutTimeout(t *testing.T) {
+	var polls int32
+	svr := newPropagatingServer(t, 3, &polls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPropagationWait(time.Millisecond, 0))
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Equal(t, int32(4), atomic.LoadInt32(&polls))
+}
+
+func TestPropagationWaitInvalid(t *testing.T) {
+	_, err := NewWebhookProvider("http://localhost:8888", WebhookWithPropagationWait(0, time.Second))
+	require.EqualError(t, err, "propagation wait interval must be positive, got 0s")
+	_, err = NewWebhookProvider("http://localhost:8888", WebhookWithPropagationWait(time.Second, -time.Second))
+	require.EqualError(t, err, "propagation wait timeout must not be negative, got -1s")
+}
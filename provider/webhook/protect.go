@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithProtectedRecords never deletes the endpoints whose DNS name matches one of the glob patterns,
+// e.g. "*.example.com", for records maintained manually. Matching is case insensitive.
+func WebhookWithProtectedRecords(patterns ...string) WebhookOption {
+	return func(p *WebhookProvider) {
+		for _, pattern := range patterns {
+			p.protectedRecords = append(p.protectedRecords, normalizeName(pattern))
+		}
+	}
+}
+
+// validateProtectedRecords checks the syntax of the protected record patterns
+func (p WebhookProvider) validateProtectedRecords() error {
+	for _, pattern := range p.protectedRecords {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected record pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isProtected returns true if e matches one of the protected record patterns
+func (p WebhookProvider) isProtected(e *endpoint.Endpoint) bool {
+	name := normalizeName(e.DNSName)
+	for _, pattern := range p.protectedRecords {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutProtectedDeletes returns a copy of changes without the deletions of protected records
+func (p WebhookProvider) withoutProtectedDeletes(changes *plan.Changes) *plan.Changes {
+	if changes == nil || len(p.protectedRecords) == 0 {
+		return changes
+	}
+	deletes := filterEndpoints(changes.Delete, func(e *endpoint.Endpoint) bool {
+		if p.isProtected(e) {
+			log.Warnf("Not deleting protected endpoint %s %s", e.DNSName, e.RecordType)
+			return false
+		}
+		return true
+	})
+	if len(deletes) == len(changes.Delete) {
+		return changes
+	}
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateOld: changes.UpdateOld,
+		UpdateNew: changes.UpdateNew,
+		Delete:    deletes,
+	}
+}
+
+// normalizeName lower cases name and removes its trailing dot
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestIsProtected(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithProtectedRecords("*.manual.example.com", "MX.example.com", "db-?.example.com")(&p)
+
+	for name, protected := range map[string]bool{
+		"a.manual.example.com":   true,
+		"A.Manual.Example.com.":  true,
+		"manual.example.com":     false,
+		"mx.example.com":         true,
+		"db-1.example.com":       true,
+		"db-10.example.com":      false,
+		"other.example.com":      false,
+		"a.manual.example.com.x": false,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, protected, p.isProtected(endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")))
+		})
+	}
+}
+
+func TestProtectedRecords(t *testing.T) {
+	var applied plan.Changes
+	svr := newApplyServer(t, &applied)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithProtectedRecords("*.manual.example.com"))
+	require.NoError(t, err)
+
+	protected := endpoint.NewEndpoint("a.manual.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	other := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	created := endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{created},
+		Delete: []*endpoint.Endpoint{protected, other},
+	}))
+	require.Len(t, applied.Create, 1)
+	require.Len(t, applied.Delete, 1)
+	require.Equal(t, "b.example.com", applied.Delete[0].DNSName)
+
+	// nothing is sent when only protected records would be deleted
+	applied = plan.Changes{Create: []*endpoint.Endpoint{created}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Delete: []*endpoint.Endpoint{protected}}))
+	require.Len(t, applied.Create, 1)
+
+	_, err = NewWebhookProvider(svr.URL, WebhookWithProtectedRecords("[a-"))
+	require.EqualError(t, err, `invalid protected record pattern "[a-": syntax error in pattern`)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// recordIDLabelKey is the label holding the ID assigned to a record by the webhook
+const recordIDLabelKey = "record-id"
+
+// recordIDCache keeps the IDs assigned by the webhook by endpoint, so that they survive the reconciles
+// of the webhooks not returning them from GET /records
+type recordIDCache struct {
+	mu  sync.Mutex
+	ids map[endpoint.EndpointKey]string
+}
+
+func newRecordIDCache() *recordIDCache {
+	return &recordIDCache{ids: map[endpoint.EndpointKey]string{}}
+}
+
+// assign sets the IDs returned by the webhook, by DNS name, on the created and updated endpoints
+// and keeps them, forgetting the IDs of the deleted endpoints
+func (c *recordIDCache) assign(changes *plan.Changes, ids map[string]string) {
+	if changes == nil {
+		return
+	}
+	if c != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, e := range changes.Delete {
+			delete(c.ids, e.Key())
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	for _, e := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if id, ok := ids[e.DNSName]; ok && id != "" {
+			if e.Labels == nil {
+				e.Labels = endpoint.NewLabels()
+			}
+			e.Labels[recordIDLabelKey] = id
+			if c != nil {
+				c.ids[e.Key()] = id
+			}
+		}
+	}
+}
+
+// restore sets the kept IDs on the endpoints without a record-id label
+func (c *recordIDCache) restore(endpoints []*endpoint.Endpoint) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range endpoints {
+		if _, ok := e.Labels[recordIDLabelKey]; ok {
+			continue
+		}
+		if id, ok := c.ids[e.Key()]; ok {
+			if e.Labels == nil {
+				e.Labels = endpoint.NewLabels()
+			}
+			e.Labels[recordIDLabelKey] = id
+		}
+	}
+}
+
+// copyRecordIDs sets the record IDs assigned to the endpoints sent by ApplyChanges
+// back on the endpoints of the plan they were copied from
+func copyRecordIDs(planned, sent *plan.Changes) {
+	if planned == nil || sent == nil {
+		return
+	}
+	copyIDs := func(to, from []*endpoint.Endpoint) {
+		if len(to) != len(from) {
+			return
+		}
+		for i, e := range from {
+			if id, ok := e.Labels[recordIDLabelKey]; ok && to[i].Labels[recordIDLabelKey] != id {
+				if to[i].Labels == nil {
+					to[i].Labels = endpoint.NewLabels()
+				}
+				to[i].Labels[recordIDLabelKey] = id
+			}
+		}
+	}
+	copyIDs(planned.Create, sent.Create)
+	copyIDs(planned.UpdateNew, sent.UpdateNew)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func newRecordIDServer(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestRecordIDs(t *testing.T) {
+	svr := newRecordIDServer(http.StatusOK, `{"recordIds": {"a.example.com": "id-a", "b.example.com": "id-b"}}`)
+	defer svr.Close()
+
+	for name, opts := range map[string][]WebhookOption{
+		"plan endpoints sent": nil,
+		"copies sent":         {WebhookWithDefaultTTL(300)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider, err := NewWebhookProvider(svr.URL, opts...)
+			require.NoError(t, err)
+
+			created := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			updated := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "5.6.7.8")
+			other := endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+				Create:    []*endpoint.Endpoint{created, other},
+				UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+				UpdateNew: []*endpoint.Endpoint{updated},
+			}))
+
+			require.Equal(t, "id-a", created.Labels[recordIDLabelKey])
+			require.Equal(t, "id-b", updated.Labels[recordIDLabelKey])
+			require.NotContains(t, other.Labels, recordIDLabelKey)
+		})
+	}
+}
+
+func TestRecordIDsNoContent(t *testing.T) {
+	svr := newRecordIDServer(http.StatusNoContent, "")
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	created := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{created}}))
+	require.Empty(t, created.Labels)
+}
+
+func TestRecordIDsKeptAcrossReconciles(t *testing.T) {
+	var stored []*endpoint.Endpoint
+	var updated *endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet:
+			// the webhook doesn't return the record IDs itself
+			require.NoError(t, json.NewEncoder(w).Encode(stored))
+		default:
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			ids := map[string]string{}
+			for _, e := range changes.Create {
+				stored = append(stored, endpoint.NewEndpoint(e.DNSName, e.RecordType, e.Targets...))
+				ids[e.DNSName] = "id-" + e.DNSName
+			}
+			if len(changes.UpdateNew) > 0 {
+				updated = changes.UpdateNew[0]
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"recordIds": ids}))
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Empty(t, records)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+
+	records, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "id-a.example.com", records[0].Labels[recordIDLabelKey])
+
+	// the ID is sent along with the updated endpoint
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		UpdateOld: records,
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "5.6.7.8")},
+	}))
+	require.NotNil(t, updated)
+	require.Equal(t, "id-a.example.com", updated.Labels[recordIDLabelKey])
+}
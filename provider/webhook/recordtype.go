@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// UnknownRecordTypePolicy defines what happens to the records of a type unknown to external-dns returned by the webhook
+type UnknownRecordTypePolicy string
+
+const (
+	// UnknownRecordTypePassThrough returns the records of unknown types unchanged
+	UnknownRecordTypePassThrough UnknownRecordTypePolicy = "pass-through"
+	// UnknownRecordTypeSkip drops the records of unknown types with a warning
+	UnknownRecordTypeSkip UnknownRecordTypePolicy = "skip"
+	// UnknownRecordTypeError fails Records when a record of an unknown type is returned
+	UnknownRecordTypeError UnknownRecordTypePolicy = "error"
+)
+
+// knownRecordTypes are the record types supported by external-dns, including the ALIAS pseudo types
+var knownRecordTypes = map[string]bool{
+	endpoint.RecordTypeA:     true,
+	endpoint.RecordTypeAAAA:  true,
+	endpoint.RecordTypeCNAME: true,
+	endpoint.RecordTypeTXT:   true,
+	endpoint.RecordTypeSRV:   true,
+	endpoint.RecordTypeNS:    true,
+	endpoint.RecordTypePTR:   true,
+	endpoint.RecordTypeMX:    true,
+	endpoint.RecordTypeCAA:   true,
+	recordTypeALIAS:          true,
+	recordTypeANAME:          true,
+}
+
+// WebhookWithUnknownRecordTypePolicy sets how the records of a type unknown to external-dns returned by Records
+// are handled. Defaults to UnknownRecordTypePassThrough.
+func WebhookWithUnknownRecordTypePolicy(policy UnknownRecordTypePolicy) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.unknownRecordTypes = policy
+	}
+}
+
+// supportsRecordType returns true if the webhook advertised the record type of e as supported,
+// logging a warning otherwise
+func (p WebhookProvider) supportsRecordType(e *endpoint.Endpoint) bool {
+	for _, recordType := range p.capabilities.RecordTypes {
+		if strings.EqualFold(recordType, e.RecordType) {
+			return true
+		}
+	}
+	log.Warnf("Skipping endpoint %s with record type %s not supported by the webhook", e.DNSName, e.RecordType)
+	return false
+}
+
+// filterUnknownRecordTypes applies the unknown record type policy to the endpoints returned by the webhook
+func (p WebhookProvider) filterUnknownRecordTypes(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	if p.unknownRecordTypes == "" || p.unknownRecordTypes == UnknownRecordTypePassThrough {
+		return endpoints, nil
+	}
+	known := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if knownRecordTypes[strings.ToUpper(e.RecordType)] {
+			known = append(known, e)
+			continue
+		}
+		if p.unknownRecordTypes == UnknownRecordTypeError {
+			return nil, fmt.Errorf("webhook returned endpoint %s with unknown record type %s", e.DNSName, e.RecordType)
+		}
+		log.Warnf("Skipping endpoint %s with unknown record type %s", e.DNSName, e.RecordType)
+	}
+	return known, nil
+}
+
+// MissingTargetsPolicy defines what happens to the records without targets returned by the webhook
+type MissingTargetsPolicy string
+
+const (
+	// MissingTargetsPassThrough returns the records without targets unchanged
+	MissingTargetsPassThrough MissingTargetsPolicy = "pass-through"
+	// MissingTargetsSkip drops the records without targets with a warning
+	MissingTargetsSkip MissingTargetsPolicy = "skip"
+	// MissingTargetsError fails Records when a record without targets is returned
+	MissingTargetsError MissingTargetsPolicy = "error"
+)
+
+// WebhookWithMissingTargetsPolicy sets how the records returned by Records without targets are handled,
+// for the record types known to external-dns, which all require targets. Defaults to MissingTargetsPassThrough.
+func WebhookWithMissingTargetsPolicy(policy MissingTargetsPolicy) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.missingTargets = policy
+	}
+}
+
+// filterMissingTargets applies the missing targets policy to the endpoints returned by the webhook
+func (p WebhookProvider) filterMissingTargets(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	if p.missingTargets == "" || p.missingTargets == MissingTargetsPassThrough {
+		return endpoints, nil
+	}
+	complete := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if len(e.Targets) > 0 || !knownRecordTypes[strings.ToUpper(e.RecordType)] {
+			complete = append(complete, e)
+			continue
+		}
+		if p.missingTargets == MissingTargetsError {
+			return nil, fmt.Errorf("webhook returned %s endpoint %s without targets", e.RecordType, e.DNSName)
+		}
+		log.Warnf("Skipping %s endpoint %s without targets", e.RecordType, e.DNSName)
+	}
+	return complete, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestUnknownRecordTypePolicy(t *testing.T) {
+	svr := newPayloadServer(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]},{"dnsName":"b.example.com","recordType":"NAPTR","targets":["100 10 \"u\" \"E2U+sip\" \"!^.*$!sip:b@example.com!\" ."]}]`)
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		policy  UnknownRecordTypePolicy
+		records int
+		err     string
+	}{
+		{policy: "", records: 2},
+		{policy: UnknownRecordTypePassThrough, records: 2},
+		{policy: UnknownRecordTypeSkip, records: 1},
+		{policy: UnknownRecordTypeError, err: "webhook returned endpoint b.example.com with unknown record type NAPTR"},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithUnknownRecordTypePolicy(tc.policy))
+			require.NoError(t, err)
+
+			endpoints, err := provider.Records(context.TODO())
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, endpoints, tc.records)
+		})
+	}
+}
+
+func TestMissingTargetsPolicy(t *testing.T) {
+	svr := newPayloadServer(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]},{"dnsName":"b.example.com","recordType":"A"},{"dnsName":"c.example.com","recordType":"NAPTR"}]`)
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		policy  MissingTargetsPolicy
+		records int
+		err     string
+	}{
+		{policy: "", records: 3},
+		{policy: MissingTargetsPassThrough, records: 3},
+		{policy: MissingTargetsSkip, records: 2},
+		{policy: MissingTargetsError, err: "webhook returned A endpoint b.example.com without targets"},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithMissingTargetsPolicy(tc.policy))
+			require.NoError(t, err)
+
+			endpoints, err := provider.Records(context.TODO())
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, endpoints, tc.records)
+			for _, e := range endpoints {
+				if tc.policy == MissingTargetsSkip {
+					require.NotEqual(t, "b.example.com", e.DNSName)
+				}
+			}
+		})
+	}
+}
+
+func TestSupportedRecordTypes(t *testing.T) {
+	var changes *plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"capabilities":{"recordTypes":["A","aaaa","TXT"]}}`))
+			return
+		}
+		changes = &plan.Changes{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(changes))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeAAAA, "::1"),
+			endpoint.NewEndpoint("mx.example.com", endpoint.RecordTypeMX, "10 mail.example.com"),
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("srv.example.com", endpoint.RecordTypeSRV, "0 50 5060 sip.example.com")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("srv.example.com", endpoint.RecordTypeSRV, "0 50 5061 sip.example.com")},
+	}))
+	require.Len(t, changes.Create, 2)
+	require.Equal(t, endpoint.RecordTypeA, changes.Create[0].RecordType)
+	require.Equal(t, endpoint.RecordTypeAAAA, changes.Create[1].RecordType)
+	require.Empty(t, changes.UpdateNew)
+
+	// nothing is sent when all the changes are of unsupported types
+	changes = nil
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("mx.example.com", endpoint.RecordTypeMX, "10 mail.example.com")},
+	}))
+	require.Nil(t, changes)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// errRecordsUnknown is returned when replacing the records before they have been fetched
+var errRecordsUnknown = errors.New("records must be fetched before replacing them")
+
+// WebhookWithApplyMethod sets the HTTP method used by ApplyChanges, POST by default.
+// With PUT, the webhook replaces all its records: instead of the changes, the full desired state is sent,
+// computed from the records returned by the webhook to the last Records call with the changes applied.
+// The records left out by Records, e.g. unmanaged or of other owners, are sent back as returned by the webhook.
+// Apply order, zone concurrency and error isolation don't apply to PUT, the state is always sent with a single request.
+func WebhookWithApplyMethod(method string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.applyMethod = strings.ToUpper(method)
+		if p.applyMethod == http.MethodPut {
+			p.knownRecords = &recordsSnapshot{}
+		}
+	}
+}
+
+// replacesRecords returns whether ApplyChanges sends the full desired state
+func (p WebhookProvider) replacesRecords() bool {
+	return p.applyMethod == http.MethodPut
+}
+
+// errSyncIncomplete is returned when replacing the records while the webhook reported an incomplete sync
+var errSyncIncomplete = errors.New("refusing to replace the records after the webhook reported an incomplete sync")
+
+// recordsSnapshot holds the records known to the webhook, as returned before the filters of Records
+type recordsSnapshot struct {
+	mu        sync.Mutex
+	fetched   bool
+	endpoints []*endpoint.Endpoint
+}
+
+func (s *recordsSnapshot) store(endpoints []*endpoint.Endpoint) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = true
+	s.endpoints = copyEndpoints(endpoints)
+}
+
+// desired returns the known records with the changes applied
+func (s *recordsSnapshot) desired(changes *plan.Changes) ([]*endpoint.Endpoint, error) {
+	if s == nil {
+		return nil, errRecordsUnknown
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.fetched {
+		return nil, errRecordsUnknown
+	}
+	if changes == nil {
+		return copyEndpoints(s.endpoints), nil
+	}
+
+	removed := map[endpoint.EndpointKey]bool{}
+	for _, e := range append(append([]*endpoint.Endpoint{}, changes.Delete...), changes.UpdateOld...) {
+		removed[snapshotKey(e)] = true
+	}
+	state := make([]*endpoint.Endpoint, 0, len(s.endpoints)+len(changes.Create))
+	for _, e := range s.endpoints {
+		if !removed[snapshotKey(e)] {
+			state = append(state, e.DeepCopy())
+		}
+	}
+	state = append(state, copyEndpoints(changes.UpdateNew)...)
+	state = append(state, copyEndpoints(changes.Create)...)
+	return state, nil
+}
+
+// snapshotKey is the key of e ignoring the normalization of the names and record types by Records
+func snapshotKey(e *endpoint.Endpoint) endpoint.EndpointKey {
+	return endpoint.EndpointKey{DNSName: normalizeName(e.DNSName), RecordType: strings.ToUpper(e.RecordType), SetIdentifier: e.SetIdentifier}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestApplyMethod(t *testing.T) {
+	var methods []string
+	var replaced []*endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[
+				{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"]},
+				{"dnsName": "b.example.com", "recordType": "A", "targets": ["1.2.3.4"]},
+				{"dnsName": "c.example.com", "recordType": "A", "targets": ["1.2.3.4"]}
+			]`))
+			return
+		case http.MethodPut:
+			replaced = nil
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&replaced))
+		}
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	changes := func() *plan.Changes {
+		return &plan.Changes{
+			Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "5.6.7.8")},
+			Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		}
+	}
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes()))
+	require.Equal(t, []string{http.MethodPost}, methods)
+
+	methods = nil
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithApplyMethod("put"))
+	require.NoError(t, err)
+	require.ErrorIs(t, provider.ApplyChanges(context.TODO(), changes()), errRecordsUnknown)
+	require.Empty(t, methods)
+
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes()))
+	require.Equal(t, []string{http.MethodPut}, methods)
+	state := map[string]endpoint.Targets{}
+	for _, e := range replaced {
+		state[e.DNSName] = e.Targets
+	}
+	require.Equal(t, map[string]endpoint.Targets{
+		"a.example.com": {"1.2.3.4"},
+		"b.example.com": {"5.6.7.8"},
+		"d.example.com": {"1.2.3.4"},
+	}, state)
+
+	// the replaced state is the base of the next changes
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	require.Len(t, replaced, 2)
+
+	_, err = NewWebhookProvider(svr.URL, WebhookWithApplyMethod("PATCH"))
+	require.EqualError(t, err, `unsupported apply method "PATCH"`)
+}
+
+func TestApplyMethodKeepsFilteredRecords(t *testing.T) {
+	const records = `[
+		{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"], "labels": {"owner": "default"}},
+		{"dnsName": "b.example.com", "recordType": "A", "targets": ["1.2.3.4"], "labels": {"owner": "default"}},
+		{"dnsName": "filtered.example.com", "recordType": %s, "targets": %s, "labels": %s, "providerSpecific": %s}
+	]`
+	for _, tc := range []struct {
+		name             string
+		opt              WebhookOption
+		recordType       string
+		targets          string
+		labels           string
+		providerSpecific string
+		replaced         []string
+	}{
+		{name: "unmanaged", opt: WebhookWithUnmanagedMarker("webhook/managed", "false"), providerSpecific: `[{"name": "webhook/managed", "value": "false"}]`},
+		{name: "unknown record type", opt: WebhookWithUnknownRecordTypePolicy(UnknownRecordTypeSkip), recordType: `"HINFO"`},
+		{name: "missing targets", opt: WebhookWithMissingTargetsPolicy(MissingTargetsSkip), targets: `[]`},
+		{name: "other owner", opt: WebhookWithOwnerFilter("default"), labels: `{"owner": "other"}`},
+		// the deletion is sent as the tombstone of a.example.com
+		{name: "tombstone", opt: WebhookWithTombstones(), providerSpecific: `[{"name": "webhook/tombstone", "value": "true"}]`, replaced: []string{"a.example.com"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			orDefault := func(v, def string) string {
+				if v == "" {
+					return def
+				}
+				return v
+			}
+			body := fmt.Sprintf(records, orDefault(tc.recordType, `"A"`), orDefault(tc.targets, `["1.2.3.4"]`),
+				orDefault(tc.labels, `{"owner": "default"}`), orDefault(tc.providerSpecific, `[]`))
+			var replaced []*endpoint.Endpoint
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+				switch {
+				case r.URL.Path == "/":
+					w.Write([]byte(`{}`))
+				case r.Method == http.MethodGet:
+					w.Write([]byte(body))
+				default:
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&replaced))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithApplyMethod(http.MethodPut), tc.opt)
+			require.NoError(t, err)
+			current, err := provider.Records(context.TODO())
+			require.NoError(t, err)
+			require.Len(t, current, 2)
+
+			require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Delete: current[:1]}))
+			var names []string
+			for _, e := range replaced {
+				names = append(names, e.DNSName)
+			}
+			require.Equal(t, append([]string{"b.example.com", "filtered.example.com"}, tc.replaced...), names)
+		})
+	}
+}
+
+func TestApplyMethodSyncIncomplete(t *testing.T) {
+	puts := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet:
+			w.Header().Set(syncCompleteHeader, "false")
+			w.Write([]byte(`[{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"]}]`))
+		default:
+			puts++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithApplyMethod(http.MethodPut))
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	// the records missing from the incomplete sync would be deleted by the replacement
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	})
+	require.ErrorIs(t, err, errSyncIncomplete)
+	require.Zero(t, puts)
+}
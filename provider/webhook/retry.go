@@ -0,0 +1,274 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrRetryBudgetExceeded is returned when the retry budget of a reconciliation is exhausted
+var ErrRetryBudgetExceeded = errors.New("retry budget exceeded")
+
+type retryBudgetContextKey struct{}
+
+// RetryBudget bounds the retries performed across all the webhook calls sharing it.
+// It is safe for concurrent use.
+type RetryBudget struct {
+	mu        sync.Mutex
+	remaining int
+	limited   bool
+	deadline  time.Time
+	exhausted bool
+}
+
+// NewRetryBudget returns a budget allowing at most maxAttempts retries within maxElapsed.
+// A zero value for either parameter means no limit on that dimension.
+func NewRetryBudget(maxAttempts int, maxElapsed time.Duration) *RetryBudget {
+	b := &RetryBudget{
+		remaining: maxAttempts,
+		limited:   maxAttempts > 0,
+	}
+	if maxElapsed > 0 {
+		b.deadline = time.Now().Add(maxElapsed)
+	}
+	return b
+}
+
+// consume takes one retry from the budget, returning ErrRetryBudgetExceeded when none is left
+func (b *RetryBudget) consume() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exhausted || (b.limited && b.remaining <= 0) || (!b.deadline.IsZero() && time.Now().After(b.deadline)) {
+		b.exhausted = true
+		return ErrRetryBudgetExceeded
+	}
+	b.remaining--
+	return nil
+}
+
+// Exhausted returns true once a retry was refused by the budget
+func (b *RetryBudget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted
+}
+
+// ContextWithRetryBudget returns a copy of ctx carrying the retry budget shared by the webhook calls using it
+func ContextWithRetryBudget(ctx context.Context, b *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetContextKey{}, b)
+}
+
+func retryBudgetFromContext(ctx context.Context) *RetryBudget {
+	b, _ := ctx.Value(retryBudgetContextKey{}).(*RetryBudget)
+	return b
+}
+
+// reconcileBudget holds the retry budget opened by the provider for the current reconciliation
+type reconcileBudget struct {
+	mu          sync.Mutex
+	maxAttempts int
+	maxElapsed  time.Duration
+	current     *RetryBudget
+}
+
+func (r *reconcileBudget) reset() *RetryBudget {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = NewRetryBudget(r.maxAttempts, r.maxElapsed)
+	return r.current
+}
+
+func (r *reconcileBudget) get() *RetryBudget {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// RetryOperation is a webhook call whose failed requests can be retried
+type RetryOperation string
+
+const (
+	// RetryOperationRecords retries GET /records, as well as the requests of ApplyChanges not changing the records:
+	// the preview of the changes and the polling of their propagation
+	RetryOperationRecords RetryOperation = "records"
+	// RetryOperationAdjustEndpoints retries POST /adjustendpoints
+	RetryOperationAdjustEndpoints RetryOperation = "adjustendpoints"
+	// RetryOperationApplyChanges retries POST /records and the requests of the transactions
+	RetryOperationApplyChanges RetryOperation = "applychanges"
+)
+
+// defaultRetriedOperations are the idempotent calls, retried unless configured otherwise
+var defaultRetriedOperations = map[RetryOperation]bool{RetryOperationRecords: true, RetryOperationAdjustEndpoints: true}
+
+// ParseRetryOperations parses comma separated operations, e.g. records,adjustendpoints
+func ParseRetryOperations(s string) ([]RetryOperation, error) {
+	var ops []RetryOperation
+	for _, v := range strings.Split(s, ",") {
+		op := RetryOperation(strings.ToLower(strings.TrimSpace(v)))
+		switch op {
+		case "":
+			continue
+		case RetryOperationRecords, RetryOperationAdjustEndpoints, RetryOperationApplyChanges:
+			ops = append(ops, op)
+		default:
+			return nil, fmt.Errorf("unknown retry operation %q", strings.TrimSpace(v))
+		}
+	}
+	return ops, nil
+}
+
+// WebhookWithRetriedOperations sets the calls whose failed requests are retried, as configured by WebhookWithRetries.
+// Only Records and AdjustEndpoints are retried by default, since retrying ApplyChanges may apply the changes twice
+// when the webhook is not idempotent.
+func WebhookWithRetriedOperations(ops ...RetryOperation) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.retriedOperations = make(map[RetryOperation]bool, len(ops))
+		for _, op := range ops {
+			p.retriedOperations[op] = true
+		}
+	}
+}
+
+// retries returns the number of retries of the failed requests of op
+func (p WebhookProvider) retries(op RetryOperation) int {
+	retried := p.retriedOperations
+	if retried == nil {
+		retried = defaultRetriedOperations
+	}
+	if !retried[op] {
+		return 0
+	}
+	return p.maxRetries
+}
+
+// WebhookWithRetries retries requests failing with a transport error or a retryable status code, 5xx and 429
+// unless configured otherwise with WebhookWithStatusClasses, up to maxRetries times
+func WebhookWithRetries(maxRetries int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.maxRetries = maxRetries
+	}
+}
+
+// WebhookWithRetryBudget shares at most maxAttempts retries within maxElapsed across all the calls of a reconciliation.
+// A new budget is opened by every call to Records, which starts each reconciliation,
+// unless the context already carries one set with ContextWithRetryBudget.
+func WebhookWithRetryBudget(maxAttempts int, maxElapsed time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.budget = &reconcileBudget{
+			maxAttempts: maxAttempts,
+			maxElapsed:  maxElapsed,
+		}
+	}
+}
+
+// retryBudget returns the budget from the context, falling back to the one of the current reconciliation
+func (p WebhookProvider) retryBudget(ctx context.Context) *RetryBudget {
+	if b := retryBudgetFromContext(ctx); b != nil {
+		return b
+	}
+	return p.budget.get()
+}
+
+func (p WebhookProvider) isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= http.StatusBadRequest && p.statusClass(resp.StatusCode) == StatusClassRetryable
+}
+
+// doWithRetry performs the request built by newRequest for op, retrying on transport errors and retryable status codes
+// as long as the retries and the retry budget allow it. The last response, if any, is returned to the caller.
+func (p WebhookProvider) doWithRetry(ctx context.Context, op RetryOperation, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	budget := p.retryBudget(ctx)
+	if budget != nil && budget.Exhausted() {
+		return nil, ErrRetryBudgetExceeded
+	}
+
+	clock := p.clockOrReal()
+	exponential := backoff.NewExponentialBackOff()
+	exponential.Clock = clock
+	exponential.Reset()
+	b := backoff.WithContext(backoff.WithMaxRetries(exponential, uint64(p.retries(op))), ctx)
+	refreshed := false
+	for {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.authenticate(req); err != nil {
+			return nil, err
+		}
+		start := clock.Now()
+		resp, err := p.client.Do(req)
+		entry := log.WithFields(log.Fields{"method": req.Method, "path": req.URL.Path, "duration": clock.Now().Sub(start)})
+		if resp != nil {
+			entry = entry.WithField("status", resp.StatusCode)
+		}
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+		entry.Debug("Webhook request completed")
+		if p.authenticator != nil && isUnauthorized(resp) {
+			resp.Body.Close()
+			if refreshed {
+				return nil, fmt.Errorf("%w: code %d after refreshing credentials", ErrWebhookUnauthorized, resp.StatusCode)
+			}
+			refreshed = true
+			if err := p.authenticator.Refresh(ctx); err != nil {
+				return nil, fmt.Errorf("failed to refresh credentials: %w", err)
+			}
+			continue
+		}
+		if !p.isRetryable(resp, err) {
+			return resp, err
+		}
+		next := b.NextBackOff()
+		if next == backoff.Stop {
+			return resp, err
+		}
+		if budget != nil {
+			if berr := budget.consume(); berr != nil {
+				if resp != nil {
+					resp.Body.Close()
+				}
+				return nil, berr
+			}
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		entry.WithField("backoff", next).Debug("Webhook request failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-clock.After(next):
+		}
+	}
+}
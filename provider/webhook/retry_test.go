@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func newFailingServer(failures int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+}
+
+func TestRecordsRetries(t *testing.T) {
+	var calls int32
+	svr := newFailingServer(1, &calls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(2))
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRetriedOperations(t *testing.T) {
+	var calls int32
+	svr := newFailingServer(100, &calls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(2), WebhookWithClock(newFakeClock()))
+	require.NoError(t, err)
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+
+	// the reads are retried, but not ApplyChanges by default
+	_, err = provider.Records(context.TODO())
+	require.EqualError(t, err, "failed to get records with code 500")
+	require.Equal(t, int32(3), atomic.SwapInt32(&calls, 0))
+	_, err = provider.AdjustEndpoints(changes.Create)
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.SwapInt32(&calls, 0))
+	err = provider.ApplyChanges(context.TODO(), changes)
+	require.EqualError(t, err, "failed to apply changes with code 500")
+	require.Equal(t, int32(1), atomic.SwapInt32(&calls, 0))
+
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithRetries(2), WebhookWithClock(newFakeClock()), WebhookWithRetriedOperations(RetryOperationApplyChanges))
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.SwapInt32(&calls, 0))
+	require.Error(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Equal(t, int32(3), atomic.SwapInt32(&calls, 0))
+}
+
+func TestParseRetryOperations(t *testing.T) {
+	ops, err := ParseRetryOperations("Records, adjustendpoints,")
+	require.NoError(t, err)
+	require.Equal(t, []RetryOperation{RetryOperationRecords, RetryOperationAdjustEndpoints}, ops)
+	_, err = ParseRetryOperations("records,propertyvaluesequal")
+	require.EqualError(t, err, `unknown retry operation "propertyvaluesequal"`)
+}
+
+func TestRetryBudgetFromContext(t *testing.T) {
+	var calls int32
+	svr := newFailingServer(100, &calls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(5))
+	require.NoError(t, err)
+
+	ctx := ContextWithRetryBudget(context.TODO(), NewRetryBudget(1, 0))
+	_, err = provider.Records(ctx)
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// once exhausted, subsequent calls fail fast without contacting the webhook
+	err = provider.ApplyChanges(ctx, &plan.Changes{})
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRetryBudgetPerReconcile(t *testing.T) {
+	var calls int32
+	svr := newFailingServer(100, &calls)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(5), WebhookWithRetryBudget(1, time.Minute))
+	require.NoError(t, err)
+
+	_, err = provider.Records(context.TODO())
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	_, err = provider.AdjustEndpoints(nil)
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{})
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// the next reconciliation starts with a fresh budget
+	_, err = provider.Records(context.TODO())
+	require.ErrorIs(t, err, ErrRetryBudgetExceeded)
+	require.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestRetryBudgetDeadline(t *testing.T) {
+	b := NewRetryBudget(0, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	require.ErrorIs(t, b.consume(), ErrRetryBudgetExceeded)
+	require.True(t, b.Exhausted())
+
+	b = NewRetryBudget(0, 0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, b.consume())
+	}
+	require.False(t, b.Exhausted())
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithPlanReview writes the changes to w before applying them, for the review of the changes in an
+// approval workflow. The changes are written as an indented PlanReview JSON document, and ApplyChanges fails
+// without applying the changes when they can't be written.
+func WebhookWithPlanReview(w io.Writer) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.planReview = &planReview{w: w}
+	}
+}
+
+// WebhookWithPlanReviewFile appends the changes to the file at path before applying them, like
+// WebhookWithPlanReview. The file is created if needed, and opened and closed around every write.
+func WebhookWithPlanReviewFile(path string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.planReview = &planReview{path: path}
+	}
+}
+
+// PlanReview is the reviewable form of the changes applied by ApplyChanges.
+// The endpoints of every operation are sorted by DNS name.
+type PlanReview struct {
+	Create []*endpoint.Endpoint `json:"create"`
+	Update []UpdateReview       `json:"update"`
+	Delete []*endpoint.Endpoint `json:"delete"`
+}
+
+// UpdateReview is an update of PlanReview, with the description of every field that changes
+type UpdateReview struct {
+	Old  *endpoint.Endpoint `json:"old"`
+	New  *endpoint.Endpoint `json:"new"`
+	Diff []string           `json:"diff"`
+}
+
+type planReview struct {
+	mu sync.Mutex
+	w  io.Writer
+	// path is the file appended to, instead of w
+	path string
+}
+
+// newPlanReview returns the review of changes
+func newPlanReview(changes *plan.Changes) PlanReview {
+	review := PlanReview{Create: []*endpoint.Endpoint{}, Update: []UpdateReview{}, Delete: []*endpoint.Endpoint{}}
+	if changes == nil {
+		return review
+	}
+	sorted := sortedChanges(changes)
+	review.Create = append(review.Create, sorted.Create...)
+	review.Delete = append(review.Delete, sorted.Delete...)
+	for i, updated := range sorted.UpdateNew {
+		if i >= len(sorted.UpdateOld) {
+			break
+		}
+		diff := diffEndpoints(sorted.UpdateOld[i], updated)
+		if diff == nil {
+			diff = []string{}
+		}
+		review.Update = append(review.Update, UpdateReview{Old: sorted.UpdateOld[i], New: updated, Diff: diff})
+	}
+	return review
+}
+
+// write writes the review of changes
+func (r *planReview) write(changes *plan.Changes) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path == "" {
+		return encodePlanReview(r.w, changes)
+	}
+	f, err := openPlanReviewFile(r.path)
+	if err != nil {
+		return err
+	}
+	if err := encodePlanReview(f, changes); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodePlanReview(w io.Writer, changes *plan.Changes) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newPlanReview(changes))
+}
+
+func openPlanReviewFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestPlanReview(t *testing.T) {
+	var applied int
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		applied++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	review := new(bytes.Buffer)
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPlanReview(review))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("d.example.com", endpoint.RecordTypeA, 300, "1.1.1.1"),
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeTXT, "old"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("d.example.com", endpoint.RecordTypeA, 60, "1.1.1.1"),
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeTXT, "new"),
+		},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("e.example.com", endpoint.RecordTypeCNAME, "target.example.com")},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Equal(t, 1, applied)
+
+	var document map[string][]json.RawMessage
+	require.NoError(t, json.Unmarshal(review.Bytes(), &document))
+	require.Len(t, document, 3)
+
+	var written PlanReview
+	require.NoError(t, json.Unmarshal(review.Bytes(), &written))
+	require.Len(t, written.Create, 2)
+	require.Equal(t, "a.example.com", written.Create[0].DNSName)
+	require.Equal(t, "b.example.com", written.Create[1].DNSName)
+	require.Len(t, written.Update, 2)
+	require.Equal(t, "c.example.com", written.Update[0].New.DNSName)
+	require.Equal(t, endpoint.Targets{"old"}, written.Update[0].Old.Targets)
+	require.Equal(t, []string{"targets added [new]", "targets removed [old]"}, written.Update[0].Diff)
+	require.Equal(t, "d.example.com", written.Update[1].New.DNSName)
+	require.Equal(t, []string{"ttl 300 -> 60"}, written.Update[1].Diff)
+	require.Len(t, written.Delete, 1)
+	require.Equal(t, "e.example.com", written.Delete[0].DNSName)
+	// the plan is left untouched
+	require.Equal(t, "b.example.com", changes.Create[0].DNSName)
+
+	// the changes are not applied when they can't be reviewed
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithPlanReview(failingWriter{}))
+	require.NoError(t, err)
+	require.EqualError(t, provider.ApplyChanges(context.TODO(), changes), "failed to write the changes for review: disk full")
+	require.Equal(t, 1, applied)
+}
+
+func TestPlanReviewEmpty(t *testing.T) {
+	b, err := json.Marshal(newPlanReview(nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"create": [], "update": [], "delete": []}`, string(b))
+}
+
+func TestPlanReviewFile(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	path := filepath.Join(t.TempDir(), "review.json")
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithPlanReviewFile(path))
+	require.NoError(t, err)
+
+	// every review is appended to the file
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}}
+		require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		var written PlanReview
+		require.NoError(t, dec.Decode(&written))
+		require.Equal(t, name, written.Create[0].DNSName)
+	}
+	require.False(t, dec.More())
+
+	// a file that can't be opened fails the changes
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithPlanReviewFile(filepath.Join(path, "review.json")))
+	require.NoError(t, err)
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	require.ErrorContains(t, provider.ApplyChanges(context.TODO(), changes), "failed to write the changes for review")
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookRoute assigns the records of a domain and its subdomains to the webhook at URL
+type WebhookRoute struct {
+	Domain string
+	URL    string
+}
+
+// ParseWebhookRoutes parses comma separated routes of the form domain=url,
+// e.g. a.example.com=http://a:8888,b.example.com=http://b:8888
+func ParseWebhookRoutes(s string) ([]WebhookRoute, error) {
+	var routes []WebhookRoute
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		domain, u, ok := strings.Cut(v, "=")
+		domain, u = strings.TrimSpace(domain), strings.TrimSpace(u)
+		if !ok || domain == "" || u == "" {
+			return nil, fmt.Errorf("invalid webhook route %q, expected domain=url", v)
+		}
+		routes = append(routes, WebhookRoute{Domain: domain, URL: u})
+	}
+	return routes, nil
+}
+
+// routedWebhook is the webhook serving the records of domain
+type routedWebhook struct {
+	domain   string
+	url      string
+	provider *WebhookProvider
+}
+
+// WebhookRouter is a provider routing the records to the webhook of their domain, for
+// multi-tenant setups where each domain is served by its own webhook.
+// Records are routed to the webhook of the most specific domain they belong to, and
+// records belonging to none of the domains can't be changed.
+type WebhookRouter struct {
+	// routes are sorted from the most specific domain
+	routes       []routedWebhook
+	domainFilter endpoint.DomainFilter
+}
+
+// NewWebhookRouter returns a router between the webhooks of routes, configured with opts
+func NewWebhookRouter(routes []WebhookRoute, opts ...WebhookOption) (*WebhookRouter, error) {
+	if len(routes) == 0 {
+		return nil, errors.New("no webhook routes configured")
+	}
+	domains := make([]string, 0, len(routes))
+	seen := map[string]bool{}
+	for _, route := range routes {
+		domain := normalizeRouteDomain(route.Domain)
+		if seen[domain] {
+			return nil, fmt.Errorf("duplicate webhook route for domain %q", domain)
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+
+	r := &WebhookRouter{}
+	for i, route := range routes {
+		domain := domains[i]
+		p, err := NewWebhookProvider(route.URL, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up the webhook of %s: %w", domain, err)
+		}
+		r.routes = append(r.routes, routedWebhook{domain: domain, url: p.remoteServerURL.Redacted(), provider: p})
+	}
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return len(r.routes[i].domain) > len(r.routes[j].domain)
+	})
+	r.domainFilter = endpoint.NewDomainFilter(domains)
+	return r, nil
+}
+
+func normalizeRouteDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// route returns the index of the route of the record named dnsName, -1 when none matches
+func (r *WebhookRouter) route(dnsName string) int {
+	name := normalizeRouteDomain(dnsName)
+	for i, route := range r.routes {
+		if name == route.domain || strings.HasSuffix(name, "."+route.domain) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Records returns the records of all the webhooks. The records returned by a webhook for a domain
+// routed to another webhook are dropped, so that each record is only managed through its route.
+func (r *WebhookRouter) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	var records []*endpoint.Endpoint
+	for i, route := range r.routes {
+		endpoints, err := route.provider.Records(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the records of %s from %s: %w", route.domain, route.url, err)
+		}
+		for _, e := range endpoints {
+			if r.route(e.DNSName) == i {
+				records = append(records, e)
+			}
+		}
+	}
+	return records, nil
+}
+
+// ApplyChanges sends each change to the webhook of its domain. It fails before applying any change
+// if a change belongs to none of the domains.
+func (r *WebhookRouter) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if changes == nil {
+		return nil
+	}
+	routed := make([]plan.Changes, len(r.routes))
+	route := func(e *endpoint.Endpoint) (int, error) {
+		i := r.route(e.DNSName)
+		if i < 0 {
+			return i, fmt.Errorf("no webhook route configured for the domain of %s", e.DNSName)
+		}
+		return i, nil
+	}
+	for _, e := range changes.Create {
+		i, err := route(e)
+		if err != nil {
+			return err
+		}
+		routed[i].Create = append(routed[i].Create, e)
+	}
+	for j, e := range changes.UpdateNew {
+		i, err := route(e)
+		if err != nil {
+			return err
+		}
+		routed[i].UpdateNew = append(routed[i].UpdateNew, e)
+		if j < len(changes.UpdateOld) {
+			routed[i].UpdateOld = append(routed[i].UpdateOld, changes.UpdateOld[j])
+		}
+	}
+	for _, e := range changes.Delete {
+		i, err := route(e)
+		if err != nil {
+			return err
+		}
+		routed[i].Delete = append(routed[i].Delete, e)
+	}
+
+	var errs []error
+	for i := range routed {
+		if !routed[i].HasChanges() {
+			continue
+		}
+		if err := r.routes[i].provider.ApplyChanges(ctx, &routed[i]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply the changes of %s to %s: %w", r.routes[i].domain, r.routes[i].url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AdjustEndpoints adjusts the endpoints with the webhook of their domain. The endpoints belonging
+// to none of the domains are returned unchanged, since they are filtered out by the domain filter.
+func (r *WebhookRouter) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	routed := make([][]*endpoint.Endpoint, len(r.routes))
+	var adjusted []*endpoint.Endpoint
+	for _, e := range endpoints {
+		if i := r.route(e.DNSName); i >= 0 {
+			routed[i] = append(routed[i], e)
+		} else {
+			adjusted = append(adjusted, e)
+		}
+	}
+	for i, route := range r.routes {
+		if len(routed[i]) == 0 {
+			continue
+		}
+		endpoints, err := route.provider.AdjustEndpoints(routed[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to adjust the endpoints of %s with %s: %w", route.domain, route.url, err)
+		}
+		adjusted = append(adjusted, endpoints...)
+	}
+	return adjusted, nil
+}
+
+// GetDomainFilter returns the filter of the domains of the routes
+func (r *WebhookRouter) GetDomainFilter() endpoint.DomainFilter {
+	return r.domainFilter
+}
+
+// PropertyComparators returns the comparators of the provider specific properties, shared by the webhooks
+func (r *WebhookRouter) PropertyComparators() map[string]plan.PropertyComparator {
+	return r.routes[0].provider.PropertyComparators()
+}
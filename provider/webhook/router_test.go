@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestParseWebhookRoutes(t *testing.T) {
+	routes, err := ParseWebhookRoutes("a.example.com=http://a:8888, example.org = http://b:8888?x=y,")
+	require.NoError(t, err)
+	require.Equal(t, []WebhookRoute{
+		{Domain: "a.example.com", URL: "http://a:8888"},
+		{Domain: "example.org", URL: "http://b:8888?x=y"},
+	}, routes)
+
+	_, err = ParseWebhookRoutes("a.example.com")
+	require.EqualError(t, err, `invalid webhook route "a.example.com", expected domain=url`)
+	_, err = ParseWebhookRoutes("=http://a:8888")
+	require.EqualError(t, err, `invalid webhook route "=http://a:8888", expected domain=url`)
+}
+
+func TestWebhookRouter(t *testing.T) {
+	parent, parentSvr := newSharedWebhook(t, false)
+	defer parentSvr.Close()
+	tenant, tenantSvr := newSharedWebhook(t, false)
+	defer tenantSvr.Close()
+	other, otherSvr := newSharedWebhook(t, false)
+	defer otherSvr.Close()
+
+	for w, records := range map[*sharedWebhook][]*endpoint.Endpoint{
+		// the parent webhook also serves the records of the tenant, which are not routed to it
+		parent: {endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.1.1.1"), endpoint.NewEndpoint("x.a.example.com", endpoint.RecordTypeA, "9.9.9.9")},
+		tenant: {endpoint.NewEndpoint("x.a.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		other:  {endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "3.3.3.3")},
+	} {
+		for _, e := range records {
+			w.records[e.Key()] = e
+		}
+	}
+
+	router, err := NewWebhookRouter([]WebhookRoute{
+		{Domain: "example.com", URL: parentSvr.URL},
+		{Domain: "A.example.com.", URL: tenantSvr.URL},
+		{Domain: "example.org", URL: otherSvr.URL},
+	})
+	require.NoError(t, err)
+	require.True(t, router.GetDomainFilter().Match("x.a.example.com"))
+	require.False(t, router.GetDomainFilter().Match("example.net"))
+
+	records, err := router.Records(context.TODO())
+	require.NoError(t, err)
+	var names []string
+	for _, e := range records {
+		names = append(names, e.DNSName+" "+e.Targets.String())
+	}
+	require.ElementsMatch(t, []string{"www.example.com 1.1.1.1", "x.a.example.com 2.2.2.2", "www.example.org 3.3.3.3"}, names)
+
+	require.NoError(t, router.ApplyChanges(context.TODO(), &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("y.a.example.com", endpoint.RecordTypeA, "4.4.4.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "3.3.3.3")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeA, "5.5.5.5")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+	}))
+	require.Equal(t, []string{"x.a.example.com  9.9.9.9"}, parent.state())
+	require.Equal(t, []string{"x.a.example.com  2.2.2.2", "y.a.example.com  4.4.4.4"}, tenant.state())
+	require.Equal(t, []string{"www.example.org  5.5.5.5"}, other.state())
+
+	// a change outside of the routes fails before any change is applied
+	err = router.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("z.example.org", endpoint.RecordTypeA, "6.6.6.6"),
+			endpoint.NewEndpoint("www.example.net", endpoint.RecordTypeA, "6.6.6.6"),
+		},
+	})
+	require.EqualError(t, err, "no webhook route configured for the domain of www.example.net")
+	require.Equal(t, []string{"www.example.org  5.5.5.5"}, other.state())
+
+	require.NoError(t, router.ApplyChanges(context.TODO(), nil))
+
+	adjusted, err := router.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("z.example.org", endpoint.RecordTypeA, "6.6.6.6"),
+		endpoint.NewEndpoint("www.example.net", endpoint.RecordTypeA, "6.6.6.6"),
+	})
+	require.NoError(t, err)
+	require.Len(t, adjusted, 2)
+}
+
+func TestWebhookRouterInvalid(t *testing.T) {
+	_, err := NewWebhookRouter(nil)
+	require.EqualError(t, err, "no webhook routes configured")
+	_, err = NewWebhookRouter([]WebhookRoute{{Domain: "example.com", URL: "http://a"}, {Domain: "Example.com.", URL: "http://b"}})
+	require.EqualError(t, err, `duplicate webhook route for domain "example.com"`)
+}
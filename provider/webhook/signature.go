@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"hash"
+	"net/http"
+)
+
+const signatureHeader = "X-Signature"
+
+// payloadSigner computes the HMAC of request bodies using a shared secret
+type payloadSigner struct {
+	secret []byte
+	hash   func() hash.Hash
+}
+
+// sign returns the hex encoded HMAC of body
+func (s *payloadSigner) sign(body []byte) string {
+	mac := hmac.New(s.hash, s.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRequest sets the signature header of req computed over body, which must be the exact bytes sent
+func (s *payloadSigner) signRequest(req *http.Request, body []byte) {
+	if s == nil {
+		return
+	}
+	req.Header.Set(signatureHeader, s.sign(body))
+}
+
+// WebhookWithHMACSignature signs the body of every POST request with the given secret and hash function,
+// e.g. sha256.New, setting the hex encoded signature in the X-Signature header.
+func WebhookWithHMACSignature(secret []byte, h func() hash.Hash) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.signer = &payloadSigner{
+			secret: secret,
+			hash:   h,
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestPayloadSignerKnownVectors(t *testing.T) {
+	// RFC 4231 test case 2
+	body := []byte("what do ya want for nothing?")
+	s := &payloadSigner{secret: []byte("Jefe"), hash: sha256.New}
+	require.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", s.sign(body))
+
+	s = &payloadSigner{secret: []byte("Jefe"), hash: sha512.New}
+	require.Equal(t, "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737", s.sign(body))
+}
+
+func TestSignedRequests(t *testing.T) {
+	secret := []byte("secret")
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(b)
+		if r.Header.Get(signatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/records":
+			w.WriteHeader(http.StatusNoContent)
+		case "/adjustendpoints":
+			w.Write(b)
+		}
+	}))
+	defer svr.Close()
+
+	endpoints := []*endpoint.Endpoint{{DNSName: "test.example.com", RecordType: "A", Targets: endpoint.Targets{"1.2.3.4"}}}
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithHMACSignature(secret, sha256.New))
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: endpoints}))
+	adjusted, err := provider.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+	require.Equal(t, endpoints, adjusted)
+
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithHMACSignature([]byte("wrong"), sha256.New))
+	require.NoError(t, err)
+	require.Error(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: endpoints}))
+
+	provider, err = NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.Error(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: endpoints}))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Sink receives a copy of the changes applied by the webhook provider, e.g. for auditing
+type Sink interface {
+	Mirror(ctx context.Context, changes *plan.Changes) error
+}
+
+// WebhookWithSinks mirrors the changes to sinks after they have been applied successfully.
+// Failing to mirror the changes is logged and does not fail ApplyChanges.
+func WebhookWithSinks(sinks ...Sink) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.sinks = append(p.sinks, sinks...)
+	}
+}
+
+func (p WebhookProvider) mirrorChanges(ctx context.Context, changes *plan.Changes) {
+	for _, s := range p.sinks {
+		if err := s.Mirror(ctx, changes); err != nil {
+			log.Warnf("Failed to mirror changes to sink: %v", err)
+		}
+	}
+}
+
+// HTTPSink posts the JSON encoded changes to an HTTP endpoint
+type HTTPSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPSink returns a HTTPSink posting to url, using http.DefaultClient if client is nil
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPSink{client: client, url: url}
+}
+
+// Mirror posts changes to the sink URL
+func (s *HTTPSink) Mirror(ctx context.Context, changes *plan.Changes) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(contentTypeHeader, "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sink %s returned code %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// WriterSink writes the changes as one line of JSON each to a writer
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a WriterSink writing to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink returns a WriterSink writing to stdout
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Mirror writes changes to the underlying writer
+func (s *WriterSink) Mirror(_ context.Context, changes *plan.Changes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(changes)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type stubSink struct {
+	mirrored []*plan.Changes
+	err      error
+}
+
+func (s *stubSink) Mirror(_ context.Context, changes *plan.Changes) error {
+	s.mirrored = append(s.mirrored, changes)
+	return s.err
+}
+
+func TestSinkMirrorsAppliedChanges(t *testing.T) {
+	var applied plan.Changes
+	svr := newApplyServer(t, &applied)
+	defer svr.Close()
+
+	failing := &stubSink{err: errors.New("sink unavailable")}
+	sink := &stubSink{}
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithSinks(failing, sink))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Len(t, failing.mirrored, 1)
+	require.Len(t, sink.mirrored, 1)
+	require.Equal(t, "a.example.com", sink.mirrored[0].Create[0].DNSName)
+}
+
+func TestSinkSkippedOnFailure(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer svr.Close()
+
+	sink := &stubSink{}
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithSinks(sink))
+	require.NoError(t, err)
+	require.Error(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Empty(t, sink.mirrored)
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer svr.Close()
+
+	changes := &plan.Changes{Delete: []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA}}}
+	require.NoError(t, NewHTTPSink(svr.URL, nil).Mirror(context.TODO(), changes))
+	require.Equal(t, "a.example.com", received.Delete[0].DNSName)
+
+	require.Error(t, NewHTTPSink(svr.URL+"/missing\x7f", nil).Mirror(context.TODO(), changes))
+}
+
+func TestWriterSink(t *testing.T) {
+	var b bytes.Buffer
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA}}}
+	require.NoError(t, NewWriterSink(&b).Mirror(context.TODO(), changes))
+
+	var written plan.Changes
+	require.NoError(t, json.Unmarshal(b.Bytes(), &written))
+	require.Equal(t, "a.example.com", written.Create[0].DNSName)
+}
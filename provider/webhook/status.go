@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// SyncStatus is the result of the last ApplyChanges call
+type SyncStatus struct {
+	Success   bool
+	Message   string
+	Timestamp time.Time
+	Creates   int
+	Updates   int
+	Deletes   int
+}
+
+// StatusWriter persists the SyncStatus of the webhook provider, e.g. for GitOps tooling
+type StatusWriter interface {
+	WriteStatus(ctx context.Context, status SyncStatus) error
+}
+
+// WebhookWithStatusWriter reports the result of every ApplyChanges call to w.
+// Failing to write the status is logged and does not fail ApplyChanges.
+func WebhookWithStatusWriter(w StatusWriter) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.statusWriter = w
+	}
+}
+
+func (p WebhookProvider) writeStatus(ctx context.Context, changes *plan.Changes, err error) {
+	status := SyncStatus{
+		Success:   err == nil,
+		Timestamp: p.clockOrReal().Now(),
+	}
+	if err != nil {
+		status.Message = err.Error()
+	}
+	if changes != nil {
+		status.Creates = len(changes.Create)
+		status.Updates = len(changes.UpdateNew)
+		status.Deletes = len(changes.Delete)
+	}
+	if werr := p.statusWriter.WriteStatus(ctx, status); werr != nil {
+		log.Warnf("Failed to write webhook sync status: %v", werr)
+	}
+}
+
+// CRDStatusWriter writes the SyncStatus to the status subresource of a custom resource
+type CRDStatusWriter struct {
+	client    dynamic.Interface
+	resource  schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// NewCRDStatusWriter returns a StatusWriter updating the status of the custom resource namespace/name
+// served as resource. The custom resource must exist and have the status subresource enabled.
+func NewCRDStatusWriter(client dynamic.Interface, resource schema.GroupVersionResource, namespace, name string) *CRDStatusWriter {
+	return &CRDStatusWriter{
+		client:    client,
+		resource:  resource,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// WriteStatus updates the status of the custom resource, retrying on conflicts
+func (w *CRDStatusWriter) WriteStatus(ctx context.Context, status SyncStatus) error {
+	ri := w.client.Resource(w.resource).Namespace(w.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := ri.Get(ctx, w.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedField(obj.Object, map[string]interface{}{
+			"success":      status.Success,
+			"message":      status.Message,
+			"lastSyncTime": status.Timestamp.UTC().Format(time.RFC3339),
+			"creates":      int64(status.Creates),
+			"updates":      int64(status.Updates),
+			"deletes":      int64(status.Deletes),
+		}, "status"); err != nil {
+			return err
+		}
+		_, err = ri.UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var syncStatusGVR = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnssyncstatuses"}
+
+type fakeStatusWriter struct {
+	statuses []SyncStatus
+	err      error
+}
+
+func (w *fakeStatusWriter) WriteStatus(ctx context.Context, status SyncStatus) error {
+	w.statuses = append(w.statuses, status)
+	return w.err
+}
+
+func TestApplyChangesWritesStatus(t *testing.T) {
+	code := http.StatusNoContent
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	defer svr.Close()
+
+	writer := &fakeStatusWriter{err: errors.New("status write failure")}
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithStatusWriter(writer))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	code = http.StatusInternalServerError
+	require.Error(t, provider.ApplyChanges(context.TODO(), changes))
+
+	require.Len(t, writer.statuses, 2)
+	require.True(t, writer.statuses[0].Success)
+	require.Equal(t, 1, writer.statuses[0].Creates)
+	require.Equal(t, 0, writer.statuses[0].Updates)
+	require.Equal(t, 1, writer.statuses[0].Deletes)
+	require.False(t, writer.statuses[1].Success)
+	require.Equal(t, "failed to apply changes with code 500", writer.statuses[1].Message)
+}
+
+func TestCRDStatusWriter(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("externaldns.k8s.io/v1alpha1")
+	obj.SetKind("DNSSyncStatus")
+	obj.SetNamespace("default")
+	obj.SetName("webhook")
+
+	client := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		syncStatusGVR: "DNSSyncStatusList",
+	}, obj)
+
+	conflicts := 0
+	client.PrependReactor("update", "dnssyncstatuses", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			conflicts++
+			return true, nil, apierrors.NewConflict(syncStatusGVR.GroupResource(), "webhook", errors.New("object was modified"))
+		}
+		return false, nil, nil
+	})
+
+	writer := NewCRDStatusWriter(client, syncStatusGVR, "default", "webhook")
+	err := writer.WriteStatus(context.TODO(), SyncStatus{
+		Success: false,
+		Message: "failed to apply changes with code 500",
+		Creates: 2,
+		Updates: 1,
+		Deletes: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, conflicts)
+
+	updated, err := client.Resource(syncStatusGVR).Namespace("default").Get(context.TODO(), "webhook", metav1.GetOptions{})
+	require.NoError(t, err)
+	status, _, err := unstructured.NestedMap(updated.Object, "status")
+	require.NoError(t, err)
+	require.Equal(t, false, status["success"])
+	require.Equal(t, "failed to apply changes with code 500", status["message"])
+	require.Equal(t, int64(2), status["creates"])
+	require.Equal(t, int64(1), status["updates"])
+	require.Equal(t, int64(3), status["deletes"])
+	require.Contains(t, status, "lastSyncTime")
+}
+
+func TestCRDStatusWriterMissingResource(t *testing.T) {
+	client := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		syncStatusGVR: "DNSSyncStatusList",
+	})
+	writer := NewCRDStatusWriter(client, syncStatusGVR, "default", "webhook")
+	err := writer.WriteStatus(context.TODO(), SyncStatus{Success: true})
+	require.True(t, apierrors.IsNotFound(err))
+}
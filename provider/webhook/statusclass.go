@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusClass is how a status code of a failed webhook request is handled
+type StatusClass string
+
+const (
+	// StatusClassRetryable retries the request, failing the call once the retries are exhausted
+	StatusClassRetryable StatusClass = "retryable"
+	// StatusClassFatal fails the call without retrying the request
+	StatusClassFatal StatusClass = "fatal"
+	// StatusClassSkip ignores the failure of POST /records with a warning, as if the changes were applied.
+	// Failures of the other requests are fatal, since skipping them could plan the deletion of every record.
+	StatusClassSkip StatusClass = "skip"
+)
+
+var (
+	// ErrWebhookRetryable is wrapped by the errors of the requests failing with a retryable status code
+	ErrWebhookRetryable = errors.New("webhook failed with a retryable status code")
+	// ErrWebhookFatal is wrapped by the errors of the requests failing with a fatal status code
+	ErrWebhookFatal = errors.New("webhook failed with a fatal status code")
+)
+
+// ParseStatusClasses parses comma separated classes of status codes, e.g. 422=fatal,409=skip
+func ParseStatusClasses(s string) (map[int]StatusClass, error) {
+	classes := map[int]StatusClass{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		code, class, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid status class %q, expected code=class", v)
+		}
+		statusCode, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil || statusCode < 100 || statusCode > 599 {
+			return nil, fmt.Errorf("invalid status code %q", strings.TrimSpace(code))
+		}
+		switch c := StatusClass(strings.ToLower(strings.TrimSpace(class))); c {
+		case StatusClassRetryable, StatusClassFatal, StatusClassSkip:
+			classes[statusCode] = c
+		default:
+			return nil, fmt.Errorf("unknown status class %q", strings.TrimSpace(class))
+		}
+	}
+	return classes, nil
+}
+
+// WebhookWithStatusClasses overrides how the failures with the given status codes are handled, for webhooks
+// using the status codes differently, e.g. 409 for a conflict to skip. By default 5xx and 429 are retryable,
+// and the other status codes are fatal.
+func WebhookWithStatusClasses(classes map[int]StatusClass) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.statusClasses == nil {
+			p.statusClasses = make(map[int]StatusClass, len(classes))
+		}
+		for code, class := range classes {
+			p.statusClasses[code] = class
+		}
+	}
+}
+
+// statusClass returns how a failure with the status code is handled
+func (p WebhookProvider) statusClass(code int) StatusClass {
+	if class, ok := p.statusClasses[code]; ok {
+		return class
+	}
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		return StatusClassRetryable
+	}
+	return StatusClassFatal
+}
+
+// statusError is the error of a request failing with a status code, wrapping the error of its class
+type statusError struct {
+	message string
+	class   StatusClass
+}
+
+// newStatusError returns the error of a request failing with code, described by message
+func (p WebhookProvider) newStatusError(code int, message string) error {
+	return &statusError{message: message, class: p.statusClass(code)}
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func (e *statusError) Unwrap() error {
+	return classError(e.class)
+}
+
+// classError returns the error wrapped by the errors of the status codes of class
+func classError(class StatusClass) error {
+	if class == StatusClassRetryable {
+		return ErrWebhookRetryable
+	}
+	return ErrWebhookFatal
+}
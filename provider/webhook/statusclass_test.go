@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestParseStatusClasses(t *testing.T) {
+	classes, err := ParseStatusClasses("422=fatal, 409 = Skip,503=retryable")
+	require.NoError(t, err)
+	require.Equal(t, map[int]StatusClass{422: StatusClassFatal, 409: StatusClassSkip, 503: StatusClassRetryable}, classes)
+
+	_, err = ParseStatusClasses("422")
+	require.EqualError(t, err, `invalid status class "422", expected code=class`)
+	_, err = ParseStatusClasses("42=fatal")
+	require.EqualError(t, err, `invalid status code "42"`)
+	_, err = ParseStatusClasses("422=ignore")
+	require.EqualError(t, err, `unknown status class "ignore"`)
+}
+
+func TestStatusClasses(t *testing.T) {
+	var calls atomic.Int32
+	var status atomic.Int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRetries(3), WebhookWithClock(newFakeClock()),
+		WebhookWithRetriedOperations(RetryOperationRecords, RetryOperationApplyChanges),
+		WebhookWithStatusClasses(map[int]StatusClass{http.StatusUnprocessableEntity: StatusClassFatal, http.StatusNotImplemented: StatusClassFatal, http.StatusConflict: StatusClassSkip}))
+	require.NoError(t, err)
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+
+	for _, tc := range []struct {
+		name   string
+		status int
+		calls  int32
+		err    error
+	}{
+		{name: "configured fatal", status: http.StatusUnprocessableEntity, calls: 1, err: ErrWebhookFatal},
+		{name: "configured fatal 5xx", status: http.StatusNotImplemented, calls: 1, err: ErrWebhookFatal},
+		{name: "default fatal", status: http.StatusBadRequest, calls: 1, err: ErrWebhookFatal},
+		{name: "default retryable", status: http.StatusServiceUnavailable, calls: 4, err: ErrWebhookRetryable},
+		{name: "default retryable 429", status: http.StatusTooManyRequests, calls: 4, err: ErrWebhookRetryable},
+		{name: "configured skip", status: http.StatusConflict, calls: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls.Store(0)
+			status.Store(int32(tc.status))
+			err := provider.ApplyChanges(context.TODO(), changes)
+			require.Equal(t, tc.calls, calls.Load())
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+			require.False(t, errors.Is(err, ErrWebhookFatal) && errors.Is(err, ErrWebhookRetryable))
+		})
+	}
+
+	// a skipped status code fails the calls other than ApplyChanges
+	status.Store(http.StatusConflict)
+	_, err = provider.Records(context.TODO())
+	require.ErrorIs(t, err, ErrWebhookFatal)
+	require.EqualError(t, err, "failed to get records with code 409")
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// syncCompleteHeader is set by webhooks to false on the Records response while they have not loaded
+// all their records yet, e.g. during the cold start of their backend
+const syncCompleteHeader = "X-Sync-Complete"
+
+// syncComplete returns false if the Records response reports that the webhook has not synced all its records.
+// A missing or invalid header means that the sync is complete.
+func syncComplete(resp *http.Response) bool {
+	v := resp.Header.Get(syncCompleteHeader)
+	if v == "" {
+		return true
+	}
+	complete, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("Ignoring invalid %s header value %q", syncCompleteHeader, v)
+		return true
+	}
+	return complete
+}
+
+// withoutDeletes returns a copy of changes without the deletions, which are not safe to apply
+// when the records returned by the webhook are incomplete
+func withoutDeletes(changes *plan.Changes) *plan.Changes {
+	if changes == nil || len(changes.Delete) == 0 {
+		return changes
+	}
+	log.Warnf("Webhook records are not fully synced, skipping %d deletions", len(changes.Delete))
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateOld: changes.UpdateOld,
+		UpdateNew: changes.UpdateNew,
+	}
+}
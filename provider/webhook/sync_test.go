@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestSyncCompleteHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		header  string
+		deletes int
+	}{
+		{name: "missing header", header: "", deletes: 1},
+		{name: "sync complete", header: "true", deletes: 1},
+		{name: "sync incomplete", header: "false", deletes: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var applied *plan.Changes
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				if r.Method == http.MethodPost {
+					applied = &plan.Changes{}
+					require.NoError(t, json.NewDecoder(r.Body).Decode(applied))
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				if tc.header != "" {
+					w.Header().Set(syncCompleteHeader, tc.header)
+				}
+				w.Write([]byte(`[]`))
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL)
+			require.NoError(t, err)
+			_, err = provider.Records(context.TODO())
+			require.NoError(t, err)
+
+			changes := &plan.Changes{
+				Create: []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA}},
+				Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA}},
+			}
+			require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+			require.NotNil(t, applied)
+			require.Len(t, applied.Create, 1)
+			require.Len(t, applied.Delete, tc.deletes)
+			// the plan is left untouched
+			require.Len(t, changes.Delete, 1)
+		})
+	}
+}
+
+func TestSyncIncompleteOnlyDeletes(t *testing.T) {
+	var posts int
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			posts++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set(syncCompleteHeader, "false")
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	changes := &plan.Changes{Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA}}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Zero(t, posts)
+}
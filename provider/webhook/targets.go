@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// WebhookWithDeduplicatedTargets removes the duplicate targets of the endpoints sent by ApplyChanges and returned
+// by AdjustEndpoints, keeping the first occurrence of each target, for webhooks rejecting records with duplicate values.
+// A warning is logged since the duplicates are caused by a misbehaving source.
+func WebhookWithDeduplicatedTargets() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.endpointTransforms = append(p.endpointTransforms, deduplicateTargets)
+		p.deduplicatedTargets = true
+	}
+}
+
+func deduplicateTargets(e *endpoint.Endpoint) {
+	seen := make(map[string]bool, len(e.Targets))
+	targets := make(endpoint.Targets, 0, len(e.Targets))
+	for _, target := range e.Targets {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == len(e.Targets) {
+		return
+	}
+	log.Warnf("Removed %d duplicate targets of %s record %s", len(e.Targets)-len(targets), e.RecordType, e.DNSName)
+	e.Targets = targets
+}
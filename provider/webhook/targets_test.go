@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestDeduplicatedTargets(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var changes plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDeduplicatedTargets())
+	require.NoError(t, err)
+
+	duplicated := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4", "1.2.3.4", "5.6.7.8")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{duplicated, endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "5.6.7.8", "1.2.3.4")},
+	}))
+	require.Equal(t, endpoint.Targets{"1.2.3.4", "5.6.7.8"}, changes.Create[0].Targets)
+	require.Equal(t, endpoint.Targets{"5.6.7.8", "1.2.3.4"}, changes.Create[1].Targets)
+	// the plan is left untouched
+	require.Equal(t, endpoint.Targets{"1.2.3.4", "1.2.3.4", "5.6.7.8"}, duplicated.Targets)
+
+	var warnings []string
+	for _, e := range hook.AllEntries() {
+		if e.Level == log.WarnLevel {
+			warnings = append(warnings, e.Message)
+		}
+	}
+	require.Equal(t, []string{"Removed 1 duplicate targets of A record a.example.com"}, warnings)
+}
+
+func TestDeduplicatedTargetsReconcile(t *testing.T) {
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDeduplicatedTargets())
+	require.NoError(t, err)
+	desired := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4", "1.2.3.4", "5.6.7.8")
+
+	require.True(t, reconcile(t, provider, "", desired))
+	require.Equal(t, []string{"a.example.com  1.2.3.4;5.6.7.8"}, webhook.state())
+	// the desired targets are deduplicated like the applied ones, so the record is not updated again
+	require.False(t, reconcile(t, provider, "", desired))
+}
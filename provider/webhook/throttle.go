@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookWithErrorLogThrottle logs the errors of Records, AdjustEndpoints and ApplyChanges, logging
+// an error identical to the previous one of the same method at most once per window, along with the
+// number of times it occurred since. This keeps the logs readable while the webhook is down.
+func WebhookWithErrorLogThrottle(window time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.errorLog = &errorLog{window: window, last: map[string]*loggedError{}}
+	}
+}
+
+// errorLog tracks the last error logged for each method
+type errorLog struct {
+	mu     sync.Mutex
+	window time.Duration
+	last   map[string]*loggedError
+}
+
+type loggedError struct {
+	message string
+	logged  time.Time
+	// suppressed is the number of occurrences not logged since logged
+	suppressed int
+}
+
+// logError logs err returned by method, unless it was already logged within the throttling window
+func (p WebhookProvider) logError(method string, err error) {
+	if p.errorLog == nil {
+		return
+	}
+	p.errorLog.log(method, err, p.clockOrReal().Now())
+}
+
+func (l *errorLog) log(method string, err error, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err == nil {
+		if last, ok := l.last[method]; ok {
+			if last.suppressed > 0 {
+				log.Infof("Webhook %s recovered after failing %d more times: %s", method, last.suppressed, last.message)
+			}
+			delete(l.last, method)
+		}
+		return
+	}
+
+	message := err.Error()
+	last, ok := l.last[method]
+	switch {
+	case !ok || last.message != message:
+		log.Errorf("Webhook %s failed: %s", method, message)
+	case now.Sub(last.logged) < l.window:
+		last.suppressed++
+		return
+	default:
+		log.Errorf("Webhook %s still failing (%d times since %s): %s", method, last.suppressed+1, last.logged.Format(time.RFC3339), message)
+	}
+	l.last[method] = &loggedError{message: message, logged: now}
+}
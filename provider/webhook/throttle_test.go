@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorLogThrottle(t *testing.T) {
+	failing := true
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+
+	clock := newFakeClock()
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithErrorLogThrottle(time.Minute), WebhookWithClock(clock))
+	require.NoError(t, err)
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	messages := func(level log.Level) []string {
+		var messages []string
+		for _, e := range hook.AllEntries() {
+			if e.Level == level {
+				messages = append(messages, e.Message)
+			}
+		}
+		hook.Reset()
+		return messages
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.Records(context.TODO())
+		require.Error(t, err)
+		clock.After(10 * time.Second)
+	}
+	require.Equal(t, []string{"Webhook Records failed: failed to get records with code 400"}, messages(log.ErrorLevel))
+
+	clock.After(time.Minute)
+	_, err = provider.Records(context.TODO())
+	require.Error(t, err)
+	require.Equal(t, []string{"Webhook Records still failing (3 times since 2023-01-01T00:00:00Z): failed to get records with code 400"}, messages(log.ErrorLevel))
+
+	_, err = provider.Records(context.TODO())
+	require.Error(t, err)
+	require.Empty(t, messages(log.ErrorLevel))
+
+	failing = false
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []string{"Webhook Records recovered after failing 1 more times: failed to get records with code 400"}, messages(log.InfoLevel))
+}
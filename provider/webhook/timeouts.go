@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import "time"
+
+// WebhookWithDialTimeout bounds the DNS resolution and TCP connection to the webhook, so that an unreachable
+// webhook fails fast. It defaults to 30s and is independent of the timeout of the requests.
+func WebhookWithDialTimeout(timeout time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.dialer.Timeout = timeout
+	}
+}
+
+// WebhookWithRequestTimeout bounds the duration of each request to the webhook, including the connection
+// and the read of the response body. No timeout is set by default.
+func WebhookWithRequestTimeout(timeout time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.client.Timeout = timeout
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialTimeout(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDialTimeout(100*time.Millisecond), WebhookWithRequestTimeout(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 100*time.Millisecond, provider.dialer.Timeout)
+	require.Equal(t, time.Minute, provider.client.Timeout)
+
+	// a non-routable address fails within the dial timeout rather than the request timeout
+	start := time.Now()
+	_, err = provider.client.Get("http://10.255.255.1:8888/records")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CertificateReloader provides a TLS client certificate read from files, reloading it when the files change,
+// e.g. when short-lived certificates are rotated on disk
+type CertificateReloader struct {
+	certFile, keyFile string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewCertificateReloader returns a CertificateReloader for the certificate and key files, which are loaded immediately
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.GetClientCertificate(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current certificate, reloading it if the files were modified.
+// When reloading fails, e.g. while the files are being replaced, the previous certificate is kept.
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cert, err := r.reload()
+	if err == nil {
+		return cert, nil
+	}
+	if r.certificate == nil {
+		return nil, fmt.Errorf("could not load TLS client certificate: %w", err)
+	}
+	log.Warnf("Failed to reload TLS client certificate, using the previous one: %v", err)
+	return r.certificate, nil
+}
+
+// reload loads the certificate if the files were modified since the last load
+func (r *CertificateReloader) reload() (*tls.Certificate, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if r.certificate != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return r.certificate, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	r.certificate = &cert
+	r.certModTime, r.keyModTime = certInfo.ModTime(), keyInfo.ModTime()
+	log.Debugf("Loaded TLS client certificate from %s", r.certFile)
+	return r.certificate, nil
+}
+
+// WebhookWithTLSConfig sets the TLS configuration of the connections to the webhook
+func WebhookWithTLSConfig(tlsConfig *tls.Config) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.transport != nil {
+			p.transport.TLSClientConfig = tlsConfig
+		}
+	}
+}
+
+// WebhookWithClientCertificate authenticates the connections to the webhook with the certificate of r,
+// taking precedence over the certificates of WebhookWithTLSConfig when given after it
+func WebhookWithClientCertificate(r *CertificateReloader) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.transport == nil {
+			return
+		}
+		if p.transport.TLSClientConfig == nil {
+			p.transport.TLSClientConfig = &tls.Config{}
+		}
+		p.transport.TLSClientConfig.GetClientCertificate = r.GetClientCertificate
+	}
+}
+
+// SPKIPin is the SHA-256 digest of the DER encoded public key (SPKI) of a certificate
+type SPKIPin [sha256.Size]byte
+
+// ParseSPKIPin parses the base64 encoded SHA-256 digest of a public key, optionally prefixed with sha256/
+// as in curl's --pinnedpubkey, e.g. computed with:
+//
+//	openssl x509 -in tls.crt -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func ParseSPKIPin(s string) (SPKIPin, error) {
+	var pin SPKIPin
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "sha256/"))
+	if err != nil {
+		return pin, fmt.Errorf("invalid SPKI pin: %w", err)
+	}
+	if len(digest) != len(pin) {
+		return pin, fmt.Errorf("invalid SPKI pin: expected a SHA-256 digest of %d bytes, got %d bytes", len(pin), len(digest))
+	}
+	copy(pin[:], digest)
+	return pin, nil
+}
+
+// WebhookWithSPKIPins rejects the connections to the webhook whose certificate doesn't have the public key
+// of one of the pins, in addition to the verification of the certificate chain, so that a compromised CA
+// can't impersonate the webhook. Several pins allow rotating the key of the webhook.
+// The pins apply to the configuration of WebhookWithTLSConfig when given after it. Without pins,
+// the connections are not pinned.
+func WebhookWithSPKIPins(pins ...SPKIPin) WebhookOption {
+	return func(p *WebhookProvider) {
+		if p.transport == nil || len(pins) == 0 {
+			return
+		}
+		if p.transport.TLSClientConfig == nil {
+			p.transport.TLSClientConfig = &tls.Config{}
+		}
+		p.transport.TLSClientConfig.VerifyConnection = verifySPKIPins(pins)
+	}
+}
+
+// verifySPKIPins returns a verification of the connections whose leaf certificate has the public key of one of the pins
+func verifySPKIPins(pins []SPKIPin) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("webhook presented no certificate to verify the SPKI pins")
+		}
+		digest := sha256.Sum256(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(digest[:], pin[:]) {
+				return nil
+			}
+		}
+		return fmt.Errorf("certificate of the webhook matches none of the SPKI pins, its pin is sha256/%s", base64.StdEncoding.EncodeToString(digest[:]))
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCertificate writes a self-signed client certificate for commonName to the files
+func writeClientCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func TestClientCertificateRotation(t *testing.T) {
+	var mu sync.Mutex
+	var clients []string
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		clients = append(clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	svr.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	svr.StartTLS()
+	defer svr.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeClientCertificate(t, certFile, keyFile, "first", time.Now().Add(-time.Minute))
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(svr.Certificate())
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithTLSConfig(&tls.Config{RootCAs: roots}), WebhookWithClientCertificate(reloader))
+	require.NoError(t, err)
+
+	writeClientCertificate(t, certFile, keyFile, "second", time.Now())
+	provider.transport.CloseIdleConnections()
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	// a broken rotation keeps the previous certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("invalid"), 0o600))
+	provider.transport.CloseIdleConnections()
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"first", "second", "second"}, clients)
+
+	_, err = NewCertificateReloader(certFile, keyFile)
+	require.ErrorContains(t, err, "could not load TLS client certificate")
+}
+
+func TestParseSPKIPin(t *testing.T) {
+	digest := sha256.Sum256([]byte("public key"))
+	encoded := base64.StdEncoding.EncodeToString(digest[:])
+	for _, s := range []string{encoded, "sha256/" + encoded, " " + encoded} {
+		pin, err := ParseSPKIPin(s)
+		require.NoError(t, err)
+		require.Equal(t, SPKIPin(digest), pin)
+	}
+
+	_, err := ParseSPKIPin("not base64")
+	require.ErrorContains(t, err, "invalid SPKI pin: illegal base64 data")
+	_, err = ParseSPKIPin(base64.StdEncoding.EncodeToString(digest[:16]))
+	require.EqualError(t, err, "invalid SPKI pin: expected a SHA-256 digest of 32 bytes, got 16 bytes")
+}
+
+func TestSPKIPins(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	svr := httptest.NewTLSServer(handler)
+	defer svr.Close()
+	// the certificate of httptest is shared by all its servers
+	other := httptest.NewUnstartedServer(handler)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeClientCertificate(t, certFile, keyFile, "other", time.Now())
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	other.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	other.StartTLS()
+	defer other.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(svr.Certificate())
+	pin := SPKIPin(sha256.Sum256(svr.Certificate().RawSubjectPublicKeyInfo))
+	var rotated SPKIPin
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithTLSConfig(&tls.Config{RootCAs: roots}), WebhookWithSPKIPins(rotated, pin))
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	// the pins are verified even when the certificate chain is not
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithTLSConfig(&tls.Config{InsecureSkipVerify: true}), WebhookWithSPKIPins(pin))
+	require.NoError(t, err)
+	_, err = provider.client.Get(other.URL)
+	require.ErrorContains(t, err, "certificate of the webhook matches none of the SPKI pins, its pin is sha256/")
+
+	// without pins, the connections are not pinned
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithTLSConfig(&tls.Config{RootCAs: roots}), WebhookWithSPKIPins())
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+}
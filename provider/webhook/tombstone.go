@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// providerSpecificTombstone marks a record as deleted, while the webhook keeps it for a retention period
+const providerSpecificTombstone = "webhook/tombstone"
+
+// WebhookWithTombstones soft-deletes the records: ApplyChanges sends the deletions as updates setting
+// the webhook/tombstone provider specific property to true, and Records leaves out the tombstoned records.
+// Creating a record again revives its tombstone with an update, since the webhook still holds it.
+// Purging the tombstones after their retention period is up to the webhook.
+func WebhookWithTombstones() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.tombstones = &tombstones{records: map[endpoint.EndpointKey]*endpoint.Endpoint{}}
+	}
+}
+
+// tombstones holds the tombstoned records returned by the last Records call
+type tombstones struct {
+	mu      sync.Mutex
+	records map[endpoint.EndpointKey]*endpoint.Endpoint
+}
+
+func isTombstoned(e *endpoint.Endpoint) bool {
+	v, ok := e.GetProviderSpecificProperty(providerSpecificTombstone)
+	return ok && v == "true"
+}
+
+// reset forgets the tombstones of the previous Records call
+func (t *tombstones) reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = map[endpoint.EndpointKey]*endpoint.Endpoint{}
+}
+
+// filter returns the endpoints which are not tombstoned, keeping the tombstoned ones
+func (t *tombstones) filter(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if t == nil {
+		return endpoints
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return filterEndpoints(endpoints, func(e *endpoint.Endpoint) bool {
+		if isTombstoned(e) {
+			t.records[e.Key()] = e
+			return false
+		}
+		// the record was revived since it was tombstoned
+		delete(t.records, e.Key())
+		return true
+	})
+}
+
+// translate returns changes with the deletions turned into updates tombstoning the records,
+// and the creations of tombstoned records turned into updates reviving them
+func (t *tombstones) translate(changes *plan.Changes) *plan.Changes {
+	if t == nil || changes == nil {
+		return changes
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	translated := &plan.Changes{
+		UpdateOld: append([]*endpoint.Endpoint{}, changes.UpdateOld...),
+		UpdateNew: append([]*endpoint.Endpoint{}, changes.UpdateNew...),
+	}
+	for _, e := range changes.Create {
+		tombstone, ok := t.records[e.Key()]
+		if !ok {
+			translated.Create = append(translated.Create, e)
+			continue
+		}
+		log.Debugf("Reviving tombstoned record %s %s", e.RecordType, e.DNSName)
+		translated.UpdateOld = append(translated.UpdateOld, tombstone)
+		translated.UpdateNew = append(translated.UpdateNew, e)
+	}
+	for _, e := range changes.Delete {
+		tombstone := e.DeepCopy()
+		tombstone.SetProviderSpecificProperty(providerSpecificTombstone, "true")
+		translated.UpdateOld = append(translated.UpdateOld, e)
+		translated.UpdateNew = append(translated.UpdateNew, tombstone)
+	}
+	return translated
+}
+
+// tombstonedCount returns the number of updates tombstoning a record, which count as deletions
+func tombstonedCount(changes *plan.Changes) int {
+	count := 0
+	for i, e := range changes.UpdateNew {
+		if isTombstoned(e) && i < len(changes.UpdateOld) && !isTombstoned(changes.UpdateOld[i]) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestTombstones(t *testing.T) {
+	w, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+	for _, e := range []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+	} {
+		w.records[e.Key()] = e
+	}
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithTombstones())
+	require.NoError(t, err)
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	// the deletion is sent as an update tombstoning the record
+	deleted := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Delete: []*endpoint.Endpoint{deleted}}))
+	tombstone := w.records[deleted.Key()]
+	require.NotNil(t, tombstone)
+	require.True(t, isTombstoned(tombstone))
+	require.Empty(t, deleted.ProviderSpecific)
+
+	// the tombstoned records are left out of the records
+	records, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "b.example.com", records[0].DNSName)
+
+	// creating the record again revives its tombstone
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "3.3.3.3")},
+	}))
+	require.Equal(t, []string{"a.example.com  3.3.3.3", "b.example.com  2.2.2.2"}, w.state())
+	require.False(t, isTombstoned(w.records[deleted.Key()]))
+	records, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+}
+
+func TestTombstonesDeletionThreshold(t *testing.T) {
+	p := WebhookProvider{maxDeletes: 1}
+	WebhookWithTombstones()(&p)
+
+	changes := p.tombstones.translate(&plan.Changes{Delete: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+	}})
+	require.Empty(t, changes.Delete)
+	require.Len(t, changes.UpdateNew, 2)
+	require.EqualError(t, p.validateChanges(changes), "refusing to delete 2 endpoints, exceeds the maximum of 1 deletions per reconcile")
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// WebhookWithTrailingDot sets whether the DNS names sent by ApplyChanges end with a trailing dot,
+// for webhooks requiring fully qualified names or rejecting them.
+// The trailing dot is removed from the DNS names returned by Records in both cases,
+// so that they compare equal to the names of the desired endpoints.
+func WebhookWithTrailingDot(trailingDot bool) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.stripTrailingDots = true
+		p.endpointTransforms = append(p.endpointTransforms, func(e *endpoint.Endpoint) {
+			e.DNSName = withTrailingDot(e.DNSName, trailingDot)
+		})
+	}
+}
+
+// withTrailingDot returns name with or without a single trailing dot
+func withTrailingDot(name string, trailingDot bool) string {
+	name = strings.TrimRight(name, ".")
+	if trailingDot && name != "" {
+		return name + "."
+	}
+	return name
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestTrailingDot(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		trailingDot bool
+		desired     string
+		sent        string
+	}{
+		{name: "added", trailingDot: true, desired: "a.example.com", sent: "a.example.com."},
+		{name: "kept", trailingDot: true, desired: "a.example.com.", sent: "a.example.com."},
+		{name: "removed", trailingDot: false, desired: "a.example.com.", sent: "a.example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the server returns the records as they were sent
+			var stored []*endpoint.Endpoint
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				if r.Method == http.MethodPost {
+					var changes plan.Changes
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+					stored = append(stored, changes.Create...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				json.NewEncoder(w).Encode(stored)
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithTrailingDot(tc.trailingDot))
+			require.NoError(t, err)
+
+			desired := []*endpoint.Endpoint{{DNSName: tc.desired, RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}}
+			require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: desired}))
+			require.Len(t, stored, 1)
+			require.Equal(t, tc.sent, stored[0].DNSName)
+			// the plan is left untouched
+			require.Equal(t, tc.desired, desired[0].DNSName)
+
+			records, err := provider.Records(context.TODO())
+			require.NoError(t, err)
+			require.Equal(t, "a.example.com", records[0].DNSName)
+
+			calculated := (&plan.Plan{
+				Policies:       []plan.Policy{&plan.SyncPolicy{}},
+				Current:        records,
+				Desired:        []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+				ManagedRecords: []string{endpoint.RecordTypeA},
+			}).Calculate()
+			require.False(t, calculated.Changes.HasChanges())
+		})
+	}
+}
+
+func TestWithTrailingDot(t *testing.T) {
+	require.Equal(t, "a.example.com.", withTrailingDot("a.example.com..", true))
+	require.Equal(t, "a.example.com", withTrailingDot("a.example.com.", false))
+	require.Equal(t, "", withTrailingDot("", true))
+}
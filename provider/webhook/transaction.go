@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const transactionIDHeader = "X-Transaction-Id"
+
+type transaction struct {
+	ID string `json:"id"`
+}
+
+// WebhookWithTransactions applies the changes within a transaction when the webhook advertises support for it.
+// A transaction is opened with a POST to /transactions, the changes are sent referencing its ID in the
+// X-Transaction-Id header, and the transaction is committed with a POST to /transactions/{id}/commit.
+// On any error the transaction is aborted with a POST to /transactions/{id}/abort, so that the whole plan
+// is considered not applied and is retried at the next reconciliation.
+func WebhookWithTransactions() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.transactions = true
+	}
+}
+
+func (p WebhookProvider) applyChangesInTransaction(ctx context.Context, changes *plan.Changes) error {
+	tx, err := p.openTransaction(ctx)
+	if err != nil {
+		applyChangesErrorsGauge.Inc()
+		return err
+	}
+
+	if err := p.applyChanges(ctx, changes, map[string]string{transactionIDHeader: tx.ID}); err != nil {
+		if abortErr := p.finishTransaction(ctx, tx, "abort"); abortErr != nil {
+			log.Errorf("Failed to abort transaction %s: %v", tx.ID, abortErr)
+		}
+		return err
+	}
+
+	if err := p.finishTransaction(ctx, tx, "commit"); err != nil {
+		applyChangesErrorsGauge.Inc()
+		return err
+	}
+	return nil
+}
+
+func (p WebhookProvider) openTransaction(ctx context.Context) (*transaction, error) {
+	u := p.remoteServerURL.JoinPath("transactions").String()
+	resp, err := p.doWithRetry(ctx, RetryOperationApplyChanges, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(acceptHeader, p.accept())
+		p.signer.signRequest(req, nil)
+		return req, nil
+	})
+	if err != nil {
+		log.Debugf("Failed to open transaction: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		log.Debugf("Failed to open transaction with code %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to open transaction with code %d", resp.StatusCode)
+	}
+
+	tx := &transaction{}
+	if err := json.NewDecoder(resp.Body).Decode(tx); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if tx.ID == "" {
+		return nil, fmt.Errorf("webhook returned a transaction without id")
+	}
+	return tx, nil
+}
+
+// finishTransaction commits or aborts the transaction depending on action
+func (p WebhookProvider) finishTransaction(ctx context.Context, tx *transaction, action string) error {
+	u := p.remoteServerURL.JoinPath("transactions", tx.ID, action).String()
+	resp, err := p.doWithRetry(ctx, RetryOperationApplyChanges, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.signer.signRequest(req, nil)
+		return req, nil
+	})
+	if err != nil {
+		log.Debugf("Failed to %s transaction %s: %s", action, tx.ID, err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Debugf("Failed to %s transaction %s with code %d", action, tx.ID, resp.StatusCode)
+		return fmt.Errorf("failed to %s transaction %s with code %d", action, tx.ID, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type transactionServer struct {
+	mu        sync.Mutex
+	advertise bool
+	applyCode int
+	calls     []string
+}
+
+func (s *transactionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/" {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		if s.advertise {
+			w.Write([]byte(`{"capabilities": {"transactions": true}}`))
+		} else {
+			w.Write([]byte(`{}`))
+		}
+		return
+	}
+	s.calls = append(s.calls, r.Method+" "+r.URL.Path+" "+r.Header.Get(transactionIDHeader))
+	switch r.URL.Path {
+	case "/transactions":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "tx1"}`))
+	case "/records":
+		w.WriteHeader(s.applyCode)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestApplyChangesTransactions(t *testing.T) {
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+
+	for _, tc := range []struct {
+		name      string
+		advertise bool
+		applyCode int
+		err       bool
+		calls     []string
+	}{
+		{
+			name:      "commit",
+			advertise: true,
+			applyCode: http.StatusNoContent,
+			calls:     []string{"POST /transactions ", "POST /records tx1", "POST /transactions/tx1/commit "},
+		},
+		{
+			name:      "abort",
+			advertise: true,
+			applyCode: http.StatusInternalServerError,
+			err:       true,
+			calls:     []string{"POST /transactions ", "POST /records tx1", "POST /transactions/tx1/abort "},
+		},
+		{
+			name:      "not supported",
+			applyCode: http.StatusNoContent,
+			calls:     []string{"POST /records "},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &transactionServer{advertise: tc.advertise, applyCode: tc.applyCode}
+			svr := httptest.NewServer(s)
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, WebhookWithTransactions())
+			require.NoError(t, err)
+			err = provider.ApplyChanges(context.TODO(), changes)
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.calls, s.calls)
+		})
+	}
+}
+
+func TestApplyChangesTransactionsDisabled(t *testing.T) {
+	s := &transactionServer{advertise: true, applyCode: http.StatusNoContent}
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Equal(t, []string{"POST /records "}, s.calls)
+}
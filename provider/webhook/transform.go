@@ -0,0 +1,240 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// providerSpecificResource is the provider specific property holding the resource that produced the endpoint
+	providerSpecificResource = "webhook/resource"
+	// providerSpecificTTLPinned marks the TTL of an endpoint as authoritative, so that it is never changed
+	// by the webhook or the provider, e.g. with the external-dns.alpha.kubernetes.io/webhook-ttl-pinned annotation
+	providerSpecificTTLPinned = "webhook/ttl-pinned"
+)
+
+// WebhookWithResourceProviderSpecific adds the resource which produced an endpoint, as found in its resource label,
+// to the webhook/resource provider specific property of the endpoints sent by ApplyChanges.
+// The property is removed from the endpoints returned by Records, so that the webhook storing it
+// doesn't make the plan update the endpoints at every reconcile.
+func WebhookWithResourceProviderSpecific() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.resourceProperty = true
+		p.endpointTransforms = append(p.endpointTransforms, addResourceProviderSpecific)
+	}
+}
+
+func addResourceProviderSpecific(e *endpoint.Endpoint) {
+	if resource, ok := e.Labels[endpoint.ResourceLabelKey]; ok && resource != "" {
+		e.SetProviderSpecificProperty(providerSpecificResource, resource)
+	}
+}
+
+// WebhookWithDefaultTTL sets ttl on the endpoints without TTL, both when returned by Records
+// and when sent by ApplyChanges, so that desired and current endpoints compare equal.
+// The default TTLs advertised by the webhook for the zones of the endpoints take precedence.
+func WebhookWithDefaultTTL(ttl endpoint.TTL) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.defaultTTL = ttl
+	}
+}
+
+// hasDefaultTTLs returns true if a default TTL is configured or advertised by the webhook
+func (p WebhookProvider) hasDefaultTTLs() bool {
+	return p.defaultTTL.IsConfigured() || len(p.zoneTTLs) > 0
+}
+
+// defaultTTLOf returns the default TTL advertised by the webhook for the most specific zone of name,
+// or the configured default TTL when the webhook advertised none
+func (p WebhookProvider) defaultTTLOf(name string) endpoint.TTL {
+	name = normalizeName(name)
+	ttl, longest := p.defaultTTL, -1
+	for zone, zoneTTL := range p.zoneTTLs {
+		if len(zone) > longest && (name == zone || strings.HasSuffix(name, "."+zone)) {
+			ttl, longest = zoneTTL, len(zone)
+		}
+	}
+	return ttl
+}
+
+// setDefaultTTLs sets the default TTL of their zone on the endpoints without TTL
+func (p WebhookProvider) setDefaultTTLs(endpoints []*endpoint.Endpoint) {
+	if !p.hasDefaultTTLs() {
+		return
+	}
+	for _, e := range endpoints {
+		if !e.RecordTTL.IsConfigured() {
+			e.RecordTTL = p.defaultTTLOf(e.DNSName)
+		}
+	}
+}
+
+// zoneDefaultTTLs returns the default TTLs advertised by the webhook keyed by normalized zone name
+func zoneDefaultTTLs(advertised map[string]endpoint.TTL) map[string]endpoint.TTL {
+	if len(advertised) == 0 {
+		return nil
+	}
+	ttls := make(map[string]endpoint.TTL, len(advertised))
+	for zone, ttl := range advertised {
+		if ttl.IsConfigured() {
+			ttls[normalizeName(zone)] = ttl
+		}
+	}
+	return ttls
+}
+
+func isTTLPinned(e *endpoint.Endpoint) bool {
+	v, ok := e.GetProviderSpecificProperty(providerSpecificTTLPinned)
+	return ok && v == "true"
+}
+
+// restorePinnedTTLs sets the pinned TTL of the endpoints sent to AdjustEndpoints back on the adjusted endpoints
+func restorePinnedTTLs(sent, adjusted []*endpoint.Endpoint) {
+	pinned := map[endpoint.EndpointKey]endpoint.TTL{}
+	for _, e := range sent {
+		if isTTLPinned(e) && e.RecordTTL.IsConfigured() {
+			pinned[e.Key()] = e.RecordTTL
+		}
+	}
+	if len(pinned) == 0 {
+		return
+	}
+	for _, e := range adjusted {
+		if ttl, ok := pinned[e.Key()]; ok && e.RecordTTL != ttl {
+			log.Debugf("Keeping pinned TTL %d of endpoint %s instead of adjusted TTL %d", ttl, e.DNSName, e.RecordTTL)
+			e.RecordTTL = ttl
+		}
+	}
+}
+
+// WebhookWithUnmanagedMarker ignores the endpoints carrying the given provider specific property or label value,
+// e.g. webhook/managed=false. Such endpoints are dropped from Records and from every change sent by ApplyChanges,
+// so that they are never created, updated or deleted.
+func WebhookWithUnmanagedMarker(key, value string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.unmanagedMarker = &marker{key: key, value: value}
+	}
+}
+
+// marker matches endpoints having a provider specific property or a label with the given value
+type marker struct {
+	key   string
+	value string
+}
+
+func (m *marker) matches(e *endpoint.Endpoint) bool {
+	if m == nil {
+		return false
+	}
+	if v, ok := e.GetProviderSpecificProperty(m.key); ok && v == m.value {
+		return true
+	}
+	if v, ok := e.Labels[m.key]; ok && v == m.value {
+		return true
+	}
+	return false
+}
+
+func (p WebhookProvider) isManaged(e *endpoint.Endpoint) bool {
+	if p.unmanagedMarker.matches(e) {
+		log.Debugf("Skipping endpoint %s marked with %s=%s", e.DNSName, p.unmanagedMarker.key, p.unmanagedMarker.value)
+		return false
+	}
+	return true
+}
+
+// filterEndpoints returns the endpoints for which keep returns true
+func filterEndpoints(endpoints []*endpoint.Endpoint, keep func(*endpoint.Endpoint) bool) []*endpoint.Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+	filtered := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if keep(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// filterChanges returns the changes for which keep returns true. An update is dropped when
+// either its old or its new endpoint is rejected, so that update pairs stay aligned.
+func filterChanges(changes *plan.Changes, keep func(*endpoint.Endpoint) bool) *plan.Changes {
+	if changes == nil {
+		return nil
+	}
+	filtered := &plan.Changes{
+		Create: filterEndpoints(changes.Create, keep),
+		Delete: filterEndpoints(changes.Delete, keep),
+	}
+	for i := range changes.UpdateNew {
+		if i >= len(changes.UpdateOld) {
+			break
+		}
+		if keep(changes.UpdateOld[i]) && keep(changes.UpdateNew[i]) {
+			filtered.UpdateOld = append(filtered.UpdateOld, changes.UpdateOld[i])
+			filtered.UpdateNew = append(filtered.UpdateNew, changes.UpdateNew[i])
+		}
+	}
+	return filtered
+}
+
+func copyEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+	c := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		c = append(c, e.DeepCopy())
+	}
+	return c
+}
+
+// modifiesChanges returns true if the endpoints sent by ApplyChanges may differ from the ones of the plan
+func (p WebhookProvider) modifiesChanges() bool {
+	return len(p.endpointTransforms) > 0 || len(p.enrichers) > 0 || p.sendsDefaultTTLs() || p.providerSpecificAllowlist != nil ||
+		p.ttlPolicy != nil && p.ttlPolicy.mode == TTLPolicyClamp || p.apexCNAME == ApexCNAMERewrite
+}
+
+// prepareChanges returns a copy of changes with the endpoint transformations applied,
+// the changes computed by the plan are never modified.
+func (p WebhookProvider) prepareChanges(changes *plan.Changes) *plan.Changes {
+	if changes == nil || !p.modifiesChanges() {
+		return changes
+	}
+	prepared := &plan.Changes{
+		Create:    copyEndpoints(changes.Create),
+		UpdateOld: copyEndpoints(changes.UpdateOld),
+		UpdateNew: copyEndpoints(changes.UpdateNew),
+		Delete:    copyEndpoints(changes.Delete),
+	}
+	if p.sendsDefaultTTLs() {
+		p.setDefaultTTLs(changesEndpoints(prepared))
+	}
+	for _, e := range changesEndpoints(prepared) {
+		for _, transform := range p.endpointTransforms {
+			transform(e)
+		}
+	}
+	return prepared
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// newApplyServer returns a webhook server storing the last changes received by ApplyChanges
+func newApplyServer(t *testing.T, applied *plan.Changes) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		require.Equal(t, http.MethodPost, r.Method)
+		*applied = plan.Changes{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(applied))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestResourceProviderSpecific(t *testing.T) {
+	var applied plan.Changes
+	svr := newApplyServer(t, &applied)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithResourceProviderSpecific())
+	require.NoError(t, err)
+
+	withResource := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	withResource.Labels[endpoint.ResourceLabelKey] = "ingress/default/a"
+	withoutResource := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")
+
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{withResource, withoutResource}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	require.Len(t, applied.Create, 2)
+	value, ok := applied.Create[0].GetProviderSpecificProperty(providerSpecificResource)
+	require.True(t, ok)
+	require.Equal(t, "ingress/default/a", value)
+	_, ok = applied.Create[1].GetProviderSpecificProperty(providerSpecificResource)
+	require.False(t, ok)
+
+	// the plan itself is not modified
+	require.Empty(t, withResource.ProviderSpecific)
+}
+
+func TestResourceProviderSpecificRoundTrip(t *testing.T) {
+	var stored []*endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet:
+			require.NoError(t, json.NewEncoder(w).Encode(stored))
+		default:
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			// the webhook stores the provider specific properties
+			stored = append(stored, changes.Create...)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithResourceProviderSpecific())
+	require.NoError(t, err)
+
+	desired := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	desired.Labels[endpoint.ResourceLabelKey] = "ingress/default/a"
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{desired}}))
+	_, ok := stored[0].GetProviderSpecificProperty(providerSpecificResource)
+	require.True(t, ok)
+
+	current, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, current, 1)
+	require.Empty(t, current[0].ProviderSpecific)
+	p := &plan.Plan{
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		Current:        current,
+		Desired:        []*endpoint.Endpoint{desired},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+	}
+	changes := p.Calculate().Changes
+	require.False(t, changes.HasChanges(), "unexpected changes: %+v", changes)
+}
+
+func TestUnmanagedMarker(t *testing.T) {
+	posts := 0
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			posts++
+			applied = plan.Changes{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&applied))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[
+			{"dnsName": "managed.example.com", "recordType": "A", "targets": ["1.2.3.4"]},
+			{"dnsName": "unmanaged.example.com", "recordType": "A", "targets": ["1.2.3.4"], "providerSpecific": [{"name": "webhook/managed", "value": "false"}]},
+			{"dnsName": "labeled.example.com", "recordType": "A", "targets": ["1.2.3.4"], "labels": {"webhook/managed": "false"}}
+		]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithUnmanagedMarker("webhook/managed", "false"))
+	require.NoError(t, err)
+
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	require.Equal(t, "managed.example.com", endpoints[0].DNSName)
+
+	unmanaged := endpoint.NewEndpoint("unmanaged.example.com", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("webhook/managed", "false")
+	updated := endpoint.NewEndpoint("unmanaged.example.com", endpoint.RecordTypeA, "5.6.7.8").WithProviderSpecific("webhook/managed", "false")
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{unmanaged},
+		UpdateNew: []*endpoint.Endpoint{updated},
+		Delete:    []*endpoint.Endpoint{unmanaged},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, posts)
+
+	managed := endpoint.NewEndpoint("managed.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{managed, updated},
+		Delete: []*endpoint.Endpoint{unmanaged},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, posts)
+	require.Len(t, applied.Create, 1)
+	require.Equal(t, "managed.example.com", applied.Create[0].DNSName)
+	require.Empty(t, applied.Delete)
+}
+
+func TestDefaultTTL(t *testing.T) {
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&applied))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"],"recordTTL":0},{"dnsName":"b.example.com","recordType":"A","targets":["1.2.3.4"],"recordTTL":60}]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDefaultTTL(300))
+	require.NoError(t, err)
+
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	require.Equal(t, endpoint.TTL(300), endpoints[0].RecordTTL)
+	require.Equal(t, endpoint.TTL(60), endpoints[1].RecordTTL)
+
+	withoutTTL := &endpoint.Endpoint{DNSName: "c.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}}
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{withoutTTL}}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Equal(t, endpoint.TTL(300), applied.Create[0].RecordTTL)
+	// the plan is left untouched
+	require.Equal(t, endpoint.TTL(0), withoutTTL.RecordTTL)
+}
+
+func TestZoneDefaultTTLs(t *testing.T) {
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"include": ["a.example.com", "b.example.com"], "defaultTTLs": {"a.example.com": 60, "B.example.com.": 600}}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&applied))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[{"dnsName":"www.a.example.com","recordType":"A","targets":["1.2.3.4"]},{"dnsName":"www.b.example.com","recordType":"A","targets":["1.2.3.4"]},{"dnsName":"www.c.example.com","recordType":"A","targets":["1.2.3.4"]}]`))
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name     string
+		opts     []WebhookOption
+		fallback endpoint.TTL
+	}{
+		{name: "without default TTL", fallback: 0},
+		{name: "with default TTL", opts: []WebhookOption{WebhookWithDefaultTTL(300)}, fallback: 300},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewWebhookProvider(svr.URL, tc.opts...)
+			require.NoError(t, err)
+
+			expected := []endpoint.TTL{60, 600, tc.fallback}
+			records, err := provider.Records(context.TODO())
+			require.NoError(t, err)
+			require.Len(t, records, 3)
+			for i, e := range records {
+				require.Equal(t, expected[i], e.RecordTTL, e.DNSName)
+			}
+
+			changes := &plan.Changes{Create: []*endpoint.Endpoint{
+				{DNSName: "www.a.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "www.b.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "www.c.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "ttl.a.example.com", RecordType: endpoint.RecordTypeA, RecordTTL: 30, Targets: endpoint.Targets{"1.2.3.4"}},
+			}}
+			require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+			for i, ttl := range append(expected, 30) {
+				require.Equal(t, ttl, applied.Create[i].RecordTTL, applied.Create[i].DNSName)
+			}
+		})
+	}
+}
+
+func TestDefaultTTLOf(t *testing.T) {
+	p := WebhookProvider{defaultTTL: 300, zoneTTLs: zoneDefaultTTLs(map[string]endpoint.TTL{"example.com": 60, "sub.example.com": 600, "other.com": 0})}
+	require.Equal(t, endpoint.TTL(60), p.defaultTTLOf("example.com"))
+	require.Equal(t, endpoint.TTL(60), p.defaultTTLOf("www.example.com."))
+	require.Equal(t, endpoint.TTL(600), p.defaultTTLOf("www.sub.example.com"))
+	require.Equal(t, endpoint.TTL(300), p.defaultTTLOf("www.other.com"))
+	require.Equal(t, endpoint.TTL(300), p.defaultTTLOf("notexample.com"))
+}
+
+func TestPinnedTTLSurvivesAdjust(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		var endpoints []*endpoint.Endpoint
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&endpoints))
+		for _, e := range endpoints {
+			e.RecordTTL = 3600
+		}
+		json.NewEncoder(w).Encode(endpoints)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	adjusted, err := provider.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("pinned.example.com", endpoint.RecordTypeA, 30, "1.2.3.4").WithProviderSpecific(providerSpecificTTLPinned, "true"),
+		endpoint.NewEndpointWithTTL("other.example.com", endpoint.RecordTypeA, 30, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+	require.Len(t, adjusted, 2)
+	require.Equal(t, endpoint.TTL(30), adjusted[0].RecordTTL)
+	require.Equal(t, endpoint.TTL(3600), adjusted[1].RecordTTL)
+}
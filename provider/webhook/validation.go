@@ -0,0 +1,400 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithMaxTargets limits the number of targets of a single record, keyed by record type.
+// Record types missing from the map are not limited.
+func WebhookWithMaxTargets(limits map[string]int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.maxTargets = limits
+	}
+}
+
+type weightValidation struct {
+	key string
+	max int64
+}
+
+// WebhookWithWeightValidation checks that the provider specific property key of the endpoints created or updated,
+// holding the weight of weighted records, is an integer between 0 and max
+func WebhookWithWeightValidation(key string, max int64) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.weightValidation = &weightValidation{key: key, max: max}
+	}
+}
+
+type labelValidation struct {
+	key   *regexp.Regexp
+	value *regexp.Regexp
+}
+
+// WebhookWithLabelValidation checks that the label keys and values of the endpoints created or updated match
+// the key and value expressions, for webhooks rejecting labels violating their format, e.g. Kubernetes label rules.
+// A nil expression is not enforced.
+func WebhookWithLabelValidation(key, value *regexp.Regexp) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.labelValidation = &labelValidation{key: key, value: value}
+	}
+}
+
+// providerSpecificRoutingPolicy is the provider specific property selecting the routing policy of a record,
+// e.g. with the external-dns.alpha.kubernetes.io/webhook-routing-policy annotation
+const providerSpecificRoutingPolicy = "webhook/routing-policy"
+
+// RoutingPolicy is a routing policy of a record, selected with the webhook/routing-policy provider specific property
+type RoutingPolicy string
+
+const (
+	RoutingPolicyWeighted RoutingPolicy = "weighted"
+	RoutingPolicyLatency  RoutingPolicy = "latency"
+	RoutingPolicyFailover RoutingPolicy = "failover"
+	RoutingPolicyGeo      RoutingPolicy = "geo"
+)
+
+var routingPolicies = []RoutingPolicy{RoutingPolicyWeighted, RoutingPolicyLatency, RoutingPolicyFailover, RoutingPolicyGeo}
+
+// validateRoutingPolicy rejects e if its routing policy is not one of the known routing policies
+func validateRoutingPolicy(e *endpoint.Endpoint) error {
+	value, ok := e.GetProviderSpecificProperty(providerSpecificRoutingPolicy)
+	if !ok {
+		return nil
+	}
+	for _, policy := range routingPolicies {
+		if RoutingPolicy(value) == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("endpoint %s has unknown routing policy %q, must be one of %v", e.DNSName, value, routingPolicies)
+}
+
+// TTLPolicyMode defines what happens to endpoints whose TTL is out of the range of a TTL policy
+type TTLPolicyMode string
+
+const (
+	// TTLPolicyReject fails ApplyChanges when an endpoint TTL is out of range
+	TTLPolicyReject TTLPolicyMode = "reject"
+	// TTLPolicyClamp sets an out of range endpoint TTL to the closest bound, in AdjustEndpoints and ApplyChanges
+	TTLPolicyClamp TTLPolicyMode = "clamp"
+)
+
+type ttlPolicy struct {
+	min  endpoint.TTL
+	max  endpoint.TTL
+	mode TTLPolicyMode
+}
+
+// WebhookWithTTLPolicy enforces that the TTL of the endpoints created or updated is within [min, max].
+// A zero bound is not enforced, nor are endpoints without TTL, which get the provider default.
+func WebhookWithTTLPolicy(min, max endpoint.TTL, mode TTLPolicyMode) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.ttlPolicy = &ttlPolicy{min: min, max: max, mode: mode}
+	}
+}
+
+// enforceMinTTL raises the minimum of the TTL policy to the minimum TTL advertised by the webhook.
+// Without a TTL policy, the TTLs below the advertised minimum are rejected.
+func (p *WebhookProvider) enforceMinTTL(min endpoint.TTL) {
+	if min <= 0 {
+		return
+	}
+	if p.ttlPolicy == nil {
+		p.ttlPolicy = &ttlPolicy{min: min, mode: TTLPolicyReject}
+		return
+	}
+	if p.ttlPolicy.min < min {
+		log.Debugf("Raising the minimum TTL from %d to %d as advertised by the webhook", p.ttlPolicy.min, min)
+		p.ttlPolicy.min = min
+	}
+}
+
+// ApexCNAMEMode defines what happens to CNAME endpoints at the apex of a zone
+type ApexCNAMEMode string
+
+const (
+	// ApexCNAMEReject fails ApplyChanges when a CNAME endpoint is at the apex of a zone
+	ApexCNAMEReject ApexCNAMEMode = "reject"
+	// ApexCNAMERewrite turns a CNAME endpoint at the apex of a zone into an ALIAS endpoint
+	ApexCNAMERewrite ApexCNAMEMode = "rewrite"
+)
+
+// WebhookWithApexCNAME detects the CNAME endpoints created or updated at the apex of a zone,
+// the zones being the domains of the domain filter negotiated with the webhook, and rejects
+// or rewrites them to ALIAS endpoints depending on mode. The rewrite is done by AdjustEndpoints too,
+// so that the desired endpoints match the ALIAS records returned by Records. A regex domain filter, having no domains, is refused.
+func WebhookWithApexCNAME(mode ApexCNAMEMode) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.apexCNAME = mode
+	}
+}
+
+// WebhookWithDeletionThreshold refuses to apply changes deleting more than maxDeletes endpoints,
+// or more than maxPercent percent of the endpoints returned by the last Records call,
+// protecting against mass deletion caused by a misbehaving source. A zero threshold is not enforced.
+func WebhookWithDeletionThreshold(maxDeletes int, maxPercent float64) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.maxDeletes = maxDeletes
+		p.maxDeletesPercent = maxPercent
+	}
+}
+
+// DomainFilterPolicy defines what happens to the endpoints not matching the domain filter negotiated with the webhook
+type DomainFilterPolicy string
+
+const (
+	// DomainFilterPolicyWarn logs a warning for every endpoint outside of the domain filter
+	DomainFilterPolicyWarn DomainFilterPolicy = "warn"
+	// DomainFilterPolicyError fails AdjustEndpoints when an endpoint is outside of the domain filter
+	DomainFilterPolicyError DomainFilterPolicy = "error"
+)
+
+// WebhookWithDomainFilterPolicy sets how endpoints outside of the domain filter negotiated with the webhook are reported.
+// Such endpoints are never applied, so they are most likely misrouted. Defaults to DomainFilterPolicyWarn.
+func WebhookWithDomainFilterPolicy(policy DomainFilterPolicy) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.domainFilterPolicy = policy
+	}
+}
+
+// checkDomainFilter reports the endpoints not matching the domain filter according to the domain filter policy
+func (p WebhookProvider) checkDomainFilter(endpoints []*endpoint.Endpoint) error {
+	if !p.DomainFilter.IsConfigured() {
+		return nil
+	}
+	for _, e := range endpoints {
+		if p.DomainFilter.Match(e.DNSName) {
+			continue
+		}
+		if p.domainFilterPolicy == DomainFilterPolicyError {
+			return fmt.Errorf("endpoint %s is not covered by the domain filter of the webhook", e.DNSName)
+		}
+		log.Warnf("Endpoint %s is not covered by the domain filter of the webhook and will be ignored", e.DNSName)
+	}
+	return nil
+}
+
+// validateChanges checks the endpoints that will be created or updated before sending them to the webhook.
+// It is called on a copy of the changes, so that out of range TTLs can be clamped.
+func (p WebhookProvider) validateChanges(changes *plan.Changes) error {
+	if changes == nil {
+		return nil
+	}
+	if err := p.validateDeletions(changes); err != nil {
+		return err
+	}
+	for _, endpoints := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew} {
+		for _, e := range endpoints {
+			if err := p.validateTargetCount(e); err != nil {
+				return err
+			}
+			if err := validateCNAMETargets(e); err != nil {
+				return err
+			}
+			if err := p.weightValidation.validate(e); err != nil {
+				return err
+			}
+			if err := p.labelValidation.validate(e); err != nil {
+				return err
+			}
+			if err := validateRoutingPolicy(e); err != nil {
+				return err
+			}
+			if err := p.ttlPolicy.enforce(e); err != nil {
+				return err
+			}
+			if err := p.checkApexCNAME(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p WebhookProvider) validateTargetCount(e *endpoint.Endpoint) error {
+	limit, ok := p.maxTargets[e.RecordType]
+	if !ok || len(e.Targets) <= limit {
+		return nil
+	}
+	return fmt.Errorf("endpoint %s has %d targets, exceeds limit %d", e.DNSName, len(e.Targets), limit)
+}
+
+// validateCNAMETargets rejects CNAME endpoints without exactly one target
+func validateCNAMETargets(e *endpoint.Endpoint) error {
+	if e.RecordType != endpoint.RecordTypeCNAME || len(e.Targets) == 1 {
+		return nil
+	}
+	return fmt.Errorf("CNAME %s must have exactly one target, got %d", e.DNSName, len(e.Targets))
+}
+
+// validate rejects e if its weight is not an integer in range
+func (w *weightValidation) validate(e *endpoint.Endpoint) error {
+	if w == nil {
+		return nil
+	}
+	value, ok := e.GetProviderSpecificProperty(w.key)
+	if !ok {
+		return nil
+	}
+	weight, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || weight < 0 || weight > w.max {
+		return fmt.Errorf("endpoint %s has invalid weight %q, must be an integer between 0 and %d", e.DNSName, value, w.max)
+	}
+	return nil
+}
+
+// validate rejects e if one of its label keys or values doesn't match the expressions
+func (l *labelValidation) validate(e *endpoint.Endpoint) error {
+	if l == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(e.Labels))
+	for key := range e.Labels {
+		keys = append(keys, key)
+	}
+	// report the same violation on every call
+	sort.Strings(keys)
+	for _, key := range keys {
+		if l.key != nil && !l.key.MatchString(key) {
+			return fmt.Errorf("endpoint %s has invalid label key %q, must match %s", e.DNSName, key, l.key)
+		}
+		if value := e.Labels[key]; l.value != nil && !l.value.MatchString(value) {
+			return fmt.Errorf("endpoint %s has invalid value %q of label %q, must match %s", e.DNSName, value, key, l.value)
+		}
+	}
+	return nil
+}
+
+// clamp clamps the TTL of e in the clamp mode, leaving the rejections to enforce
+func (t *ttlPolicy) clamp(e *endpoint.Endpoint) {
+	if t == nil || t.mode != TTLPolicyClamp {
+		return
+	}
+	// the clamp mode never fails
+	_ = t.enforce(e)
+}
+
+// enforce rejects or clamps the TTL of e depending on the policy mode
+func (t *ttlPolicy) enforce(e *endpoint.Endpoint) error {
+	if t == nil || !e.RecordTTL.IsConfigured() {
+		return nil
+	}
+	bound := e.RecordTTL
+	if t.min > 0 && e.RecordTTL < t.min {
+		bound = t.min
+	} else if t.max > 0 && e.RecordTTL > t.max {
+		bound = t.max
+	}
+	if bound == e.RecordTTL {
+		return nil
+	}
+	if t.mode == TTLPolicyClamp {
+		if isTTLPinned(e) {
+			log.Warnf("Not clamping pinned TTL %d of endpoint %s", e.RecordTTL, e.DNSName)
+			return nil
+		}
+		log.Warnf("Clamping TTL of endpoint %s from %d to %d", e.DNSName, e.RecordTTL, bound)
+		e.RecordTTL = bound
+		return nil
+	}
+	return fmt.Errorf("endpoint %s has TTL %d, outside of the allowed range %s", e.DNSName, e.RecordTTL, t)
+}
+
+// String returns the allowed range of the TTLs, the maximum being unbounded when not configured
+func (t *ttlPolicy) String() string {
+	if t.max <= 0 {
+		return fmt.Sprintf("[%d, unbounded]", t.min)
+	}
+	return fmt.Sprintf("[%d, %d]", t.min, t.max)
+}
+
+// rewriteApexCNAMEs rewrites the CNAME endpoints at the apex of a zone to ALIAS endpoints in the rewrite mode,
+// so that the desired endpoints match the ALIAS records returned by Records
+func (p WebhookProvider) rewriteApexCNAMEs(endpoints []*endpoint.Endpoint) {
+	if p.apexCNAME != ApexCNAMERewrite {
+		return
+	}
+	for _, e := range endpoints {
+		// the rewrite mode never fails
+		_ = p.checkApexCNAME(e)
+	}
+}
+
+// checkApexCNAME rejects or rewrites e if it is a CNAME at the apex of a zone
+func (p WebhookProvider) checkApexCNAME(e *endpoint.Endpoint) error {
+	if p.apexCNAME == "" || e.RecordType != endpoint.RecordTypeCNAME || !p.isApex(e.DNSName) {
+		return nil
+	}
+	if p.apexCNAME == ApexCNAMERewrite {
+		log.Infof("Rewriting CNAME endpoint %s at zone apex to %s", e.DNSName, recordTypeALIAS)
+		e.RecordType = recordTypeALIAS
+		e.SetProviderSpecificProperty(providerSpecificAlias, "true")
+		return nil
+	}
+	return fmt.Errorf("endpoint %s is a CNAME at the apex of a zone, which is not supported by the webhook", e.DNSName)
+}
+
+// isApex returns true if name is one of the domains of the domain filter
+func (p WebhookProvider) isApex(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, zone := range p.DomainFilter.Filters {
+		if strings.TrimPrefix(zone, ".") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// storeRecordsCount keeps the number of endpoints returned by Records that the plan can delete,
+// the endpoints outside of the domain filter being left alone by the plan
+func (p WebhookProvider) storeRecordsCount(endpoints []*endpoint.Endpoint) {
+	if p.recordsCount == nil {
+		return
+	}
+	n := 0
+	for _, e := range endpoints {
+		if p.DomainFilter.Match(e.DNSName) {
+			n++
+		}
+	}
+	p.recordsCount.Store(int64(n))
+}
+
+func (p WebhookProvider) validateDeletions(changes *plan.Changes) error {
+	deletes := len(changes.Delete) + tombstonedCount(changes)
+	if p.maxDeletes > 0 && deletes > p.maxDeletes {
+		return fmt.Errorf("refusing to delete %d endpoints, exceeds the maximum of %d deletions per reconcile", deletes, p.maxDeletes)
+	}
+	if p.maxDeletesPercent > 0 && p.recordsCount != nil {
+		records := p.recordsCount.Load()
+		if records > 0 && float64(deletes)*100/float64(records) > p.maxDeletesPercent {
+			return fmt.Errorf("refusing to delete %d of %d endpoints, exceeds the maximum of %g%% deletions per reconcile", deletes, records, p.maxDeletesPercent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func targets(n int) endpoint.Targets {
+	t := endpoint.Targets{}
+	for i := 0; i < n; i++ {
+		t = append(t, fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	return t
+}
+
+func TestValidateTargetCount(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithMaxTargets(map[string]int{endpoint.RecordTypeA: 100})(&p)
+
+	for _, tc := range []struct {
+		name    string
+		changes *plan.Changes
+		err     string
+	}{
+		{
+			name:    "at limit",
+			changes: &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, targets(100)...)}},
+		},
+		{
+			name:    "above limit",
+			changes: &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, targets(150)...)}},
+			err:     "endpoint foo.example.com has 150 targets, exceeds limit 100",
+		},
+		{
+			name:    "above limit on update",
+			changes: &plan.Changes{UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, targets(101)...)}},
+			err:     "endpoint foo.example.com has 101 targets, exceeds limit 100",
+		},
+		{
+			name:    "deletes are not limited",
+			changes: &plan.Changes{Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, targets(150)...)}},
+		},
+		{
+			name:    "unlimited record type",
+			changes: &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeTXT, targets(150)...)}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.validateChanges(tc.changes)
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidateCNAMETargets(t *testing.T) {
+	p := WebhookProvider{}
+
+	for _, tc := range []struct {
+		name    string
+		targets []string
+		err     string
+	}{
+		{name: "no target", err: "CNAME foo.example.com must have exactly one target, got 0"},
+		{name: "one target", targets: []string{"bar.example.com"}},
+		{name: "two targets", targets: []string{"bar.example.com", "baz.example.com"}, err: "CNAME foo.example.com must have exactly one target, got 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cname := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeCNAME, tc.targets...)
+			err := p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{cname}})
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+			require.Equal(t, err, p.validateChanges(&plan.Changes{UpdateNew: []*endpoint.Endpoint{cname}}))
+			require.NoError(t, p.validateChanges(&plan.Changes{Delete: []*endpoint.Endpoint{cname}}))
+		})
+	}
+}
+
+func TestWeightValidation(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithWeightValidation("webhook/weight", 255)(&p)
+
+	for _, tc := range []struct {
+		name   string
+		weight string
+		err    string
+	}{
+		{name: "valid", weight: "10"},
+		{name: "zero", weight: "0"},
+		{name: "maximum", weight: "255"},
+		{name: "negative", weight: "-1", err: `endpoint foo.example.com has invalid weight "-1", must be an integer between 0 and 255`},
+		{name: "too large", weight: "256", err: `endpoint foo.example.com has invalid weight "256", must be an integer between 0 and 255`},
+		{name: "non-numeric", weight: "heavy", err: `endpoint foo.example.com has invalid weight "heavy", must be an integer between 0 and 255`},
+		{name: "decimal", weight: "1.5", err: `endpoint foo.example.com has invalid weight "1.5", must be an integer between 0 and 255`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("webhook/weight", tc.weight)
+			err := p.validateChanges(&plan.Changes{UpdateNew: []*endpoint.Endpoint{e}})
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+		})
+	}
+
+	// endpoints without weight are not weighted
+	require.NoError(t, p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")}}))
+}
+
+func TestLabelValidation(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithLabelValidation(
+		regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`),
+		regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`),
+	)(&p)
+
+	for _, tc := range []struct {
+		name   string
+		labels endpoint.Labels
+		err    string
+	}{
+		{name: "valid", labels: endpoint.Labels{"owner": "default", "example.com/team": "dns-team"}},
+		{name: "empty value", labels: endpoint.Labels{"team": ""}},
+		{name: "invalid key", labels: endpoint.Labels{"owner": "default", "-team": "dns"}, err: `endpoint foo.example.com has invalid label key "-team", must match ^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`},
+		{name: "invalid value", labels: endpoint.Labels{"resource": "ingress/default/foo"}, err: `endpoint foo.example.com has invalid value "ingress/default/foo" of label "resource", must match ^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")
+			e.Labels = tc.labels
+			err := p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{e}})
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+		})
+	}
+
+	// the labels of the deleted endpoints are not validated
+	e := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	e.Labels = endpoint.Labels{"-team": "dns"}
+	require.NoError(t, p.validateChanges(&plan.Changes{Delete: []*endpoint.Endpoint{e}}))
+}
+
+func TestLabelValidationBeforeApply(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithLabelValidation(nil, regexp.MustCompile(`^[a-z]*$`)))
+	require.NoError(t, err)
+	e := endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	e.Labels = endpoint.Labels{"team": "DNS"}
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{e}})
+	require.EqualError(t, err, `endpoint foo.example.com has invalid value "DNS" of label "team", must match ^[a-z]*$`)
+}
+
+func TestRoutingPolicy(t *testing.T) {
+	// the server returns the records as they were sent
+	var stored []*endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			stored = append(stored, changes.Create...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		policy string
+		err    string
+	}{
+		{policy: "weighted"},
+		{policy: "latency"},
+		{policy: "failover"},
+		{policy: "geo"},
+		{policy: "random", err: `endpoint a.example.com has unknown routing policy "random", must be one of [weighted latency failover geo]`},
+		{policy: "Latency", err: `endpoint a.example.com has unknown routing policy "Latency", must be one of [weighted latency failover geo]`},
+		{policy: "", err: `endpoint a.example.com has unknown routing policy "", must be one of [weighted latency failover geo]`},
+	} {
+		t.Run("policy "+tc.policy, func(t *testing.T) {
+			stored = nil
+			e := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific(providerSpecificRoutingPolicy, tc.policy)
+			err := provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{e}})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				require.Empty(t, stored)
+				return
+			}
+			require.NoError(t, err)
+
+			records, err := provider.Records(context.TODO())
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			policy, ok := records[0].GetProviderSpecificProperty(providerSpecificRoutingPolicy)
+			require.True(t, ok)
+			require.Equal(t, tc.policy, policy)
+		})
+	}
+}
+
+func TestTTLPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		mode TTLPolicyMode
+		ttl  endpoint.TTL
+		want endpoint.TTL
+		err  string
+	}{
+		{name: "in range", mode: TTLPolicyReject, ttl: 300, want: 300},
+		{name: "unset", mode: TTLPolicyReject, ttl: 0, want: 0},
+		{name: "reject below floor", mode: TTLPolicyReject, ttl: 1, err: "endpoint foo.example.com has TTL 1, outside of the allowed range [60, 86400]"},
+		{name: "reject above ceiling", mode: TTLPolicyReject, ttl: 604800, err: "endpoint foo.example.com has TTL 604800, outside of the allowed range [60, 86400]"},
+		{name: "clamp below floor", mode: TTLPolicyClamp, ttl: 1, want: 60},
+		{name: "clamp above ceiling", mode: TTLPolicyClamp, ttl: 604800, want: 86400},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := WebhookProvider{}
+			WebhookWithTTLPolicy(60, 86400, tc.mode)(&p)
+
+			e := endpoint.NewEndpointWithTTL("foo.example.com", endpoint.RecordTypeA, tc.ttl, "1.2.3.4")
+			err := p.validateChanges(&plan.Changes{UpdateNew: []*endpoint.Endpoint{e}})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, e.RecordTTL)
+		})
+	}
+}
+
+func TestTTLPolicyWithoutMaximum(t *testing.T) {
+	p := WebhookProvider{}
+	WebhookWithTTLPolicy(60, 0, TTLPolicyReject)(&p)
+
+	e := endpoint.NewEndpointWithTTL("foo.example.com", endpoint.RecordTypeA, 604800, "1.2.3.4")
+	require.NoError(t, p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{e}}))
+	e = endpoint.NewEndpointWithTTL("foo.example.com", endpoint.RecordTypeA, 1, "1.2.3.4")
+	require.EqualError(t, p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{e}}), "endpoint foo.example.com has TTL 1, outside of the allowed range [60, unbounded]")
+}
+
+func TestTTLPolicyClampReconcile(t *testing.T) {
+	webhook, svr := newSharedWebhook(t, false)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithTTLPolicy(60, 3600, TTLPolicyClamp))
+	require.NoError(t, err)
+	desired := endpoint.NewEndpointWithTTL("foo.example.com", endpoint.RecordTypeA, 30, "1.2.3.4")
+
+	require.True(t, reconcile(t, provider, "", desired))
+	require.Equal(t, endpoint.TTL(60), webhook.records[desired.Key()].RecordTTL)
+	// the desired TTL is clamped like the applied one, so the record is not updated again
+	require.False(t, reconcile(t, provider, "", desired))
+	require.Equal(t, endpoint.TTL(30), desired.RecordTTL)
+}
+
+func TestAdvertisedMinTTL(t *testing.T) {
+	var applied []plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"capabilities": {"minTTL": 60}}`))
+			return
+		}
+		var changes plan.Changes
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		applied = append(applied, changes)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name string
+		opts []WebhookOption
+		want endpoint.TTL
+		err  string
+	}{
+		{name: "rejected without policy", err: "endpoint foo.example.com has TTL 30, outside of the allowed range [60, unbounded]"},
+		{name: "rejected by policy", opts: []WebhookOption{WebhookWithTTLPolicy(10, 3600, TTLPolicyReject)}, err: "endpoint foo.example.com has TTL 30, outside of the allowed range [60, 3600]"},
+		{name: "clamped by policy", opts: []WebhookOption{WebhookWithTTLPolicy(10, 3600, TTLPolicyClamp)}, want: 60},
+		{name: "higher configured minimum", opts: []WebhookOption{WebhookWithTTLPolicy(120, 3600, TTLPolicyClamp)}, want: 120},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			applied = nil
+			provider, err := NewWebhookProvider(svr.URL, tc.opts...)
+			require.NoError(t, err)
+
+			e := endpoint.NewEndpointWithTTL("foo.example.com", endpoint.RecordTypeA, 30, "1.2.3.4")
+			err = provider.ApplyChanges(context.TODO(), &plan.Changes{Create: []*endpoint.Endpoint{e}})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				require.Empty(t, applied)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, applied, 1)
+			require.Equal(t, tc.want, applied[0].Create[0].RecordTTL)
+		})
+	}
+}
+
+func TestApexCNAME(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mode     ApexCNAMEMode
+		dnsName  string
+		wantType string
+		err      string
+	}{
+		{name: "rejected at apex", mode: ApexCNAMEReject, dnsName: "example.com", err: "endpoint example.com is a CNAME at the apex of a zone, which is not supported by the webhook"},
+		{name: "rewritten at apex", mode: ApexCNAMERewrite, dnsName: "example.com.", wantType: recordTypeALIAS},
+		{name: "below apex", mode: ApexCNAMEReject, dnsName: "www.example.com", wantType: endpoint.RecordTypeCNAME},
+		{name: "not configured", dnsName: "example.com", wantType: endpoint.RecordTypeCNAME},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := WebhookProvider{}
+			require.NoError(t, json.Unmarshal([]byte(`{"include":["example.com","other.org"]}`), &p.DomainFilter))
+			WebhookWithApexCNAME(tc.mode)(&p)
+
+			e := endpoint.NewEndpoint(tc.dnsName, endpoint.RecordTypeCNAME, "lb.example.net")
+			err := p.validateChanges(&plan.Changes{Create: []*endpoint.Endpoint{e}})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantType, e.RecordType)
+			if tc.wantType == recordTypeALIAS {
+				v, ok := e.GetProviderSpecificProperty(providerSpecificAlias)
+				require.True(t, ok)
+				require.Equal(t, "true", v)
+			}
+		})
+	}
+}
+
+func TestApexCNAMERewriteReconciles(t *testing.T) {
+	var stored []*endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{"include":["example.com"]}`))
+		case r.URL.Path == "/adjustendpoints":
+			io.Copy(w, r.Body)
+		case r.Method == http.MethodGet:
+			require.NoError(t, json.NewEncoder(w).Encode(stored))
+		default:
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			stored = append(stored, changes.Create...)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithApexCNAME(ApexCNAMERewrite))
+	require.NoError(t, err)
+	reconcile := func() *plan.Changes {
+		current, err := provider.Records(context.TODO())
+		require.NoError(t, err)
+		desired, err := provider.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint("example.com", endpoint.RecordTypeCNAME, "lb.example.net")})
+		require.NoError(t, err)
+		p := &plan.Plan{
+			Policies:       []plan.Policy{&plan.SyncPolicy{}},
+			Current:        current,
+			Desired:        desired,
+			ManagedRecords: []string{endpoint.RecordTypeCNAME, recordTypeALIAS},
+		}
+		changes := p.Calculate().Changes
+		require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+		return changes
+	}
+
+	changes := reconcile()
+	require.Len(t, changes.Create, 1)
+	require.Equal(t, recordTypeALIAS, changes.Create[0].RecordType)
+	require.Len(t, stored, 1)
+
+	changes = reconcile()
+	require.False(t, changes.HasChanges(), "unexpected changes: %+v", changes)
+}
+
+func deletions(n int) *plan.Changes {
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.com", endpoint.RecordTypeA, "1.2.3.4")}}
+	for i := 0; i < n; i++ {
+		changes.Delete = append(changes.Delete, endpoint.NewEndpoint(fmt.Sprintf("%d.example.com", i), endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return changes
+}
+
+func TestDeletionThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxDeletes int
+		maxPercent float64
+		deletes    int
+		err        string
+	}{
+		{name: "not configured", deletes: 100},
+		{name: "below count", maxDeletes: 5, deletes: 5},
+		{name: "above count", maxDeletes: 5, deletes: 6, err: "refusing to delete 6 endpoints, exceeds the maximum of 5 deletions per reconcile"},
+		{name: "below percentage", maxPercent: 50, deletes: 10},
+		{name: "above percentage", maxPercent: 50, deletes: 11, err: "refusing to delete 11 of 20 endpoints, exceeds the maximum of 50% deletions per reconcile"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := WebhookProvider{recordsCount: &atomic.Int64{}}
+			p.recordsCount.Store(20)
+			WebhookWithDeletionThreshold(tc.maxDeletes, tc.maxPercent)(&p)
+
+			err := p.validateChanges(deletions(tc.deletes))
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeletionThresholdFilteredRecords(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"include":["example.com"]}`))
+			return
+		}
+		w.Write([]byte(`[
+			{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"default"}},
+			{"dnsName":"b.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"default"}},
+			{"dnsName":"c.example.com","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"other"}},
+			{"dnsName":"d.other.org","recordType":"A","targets":["1.2.3.4"],"labels":{"owner":"default"}}
+		]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithOwnerFilter("default"), WebhookWithDeletionThreshold(0, 40))
+	require.NoError(t, err)
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	// the percentage is of the records of the owner within the domain filter
+	err = provider.validateChanges(&plan.Changes{Delete: records[:1]})
+	require.EqualError(t, err, "refusing to delete 1 of 2 endpoints, exceeds the maximum of 40% deletions per reconcile")
+}
+
+func TestDomainFilterPolicy(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"include":["example.com"]}`))
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name   string
+		policy DomainFilterPolicy
+		err    string
+	}{
+		{name: "default"},
+		{name: "warn", policy: DomainFilterPolicyWarn},
+		{name: "error", policy: DomainFilterPolicyError, err: "endpoint foo.other.org is not covered by the domain filter of the webhook"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewWebhookProvider(svr.URL, WebhookWithDomainFilterPolicy(tc.policy))
+			require.NoError(t, err)
+
+			_, err = p.AdjustEndpoints([]*endpoint.Endpoint{
+				endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+				endpoint.NewEndpoint("foo.other.org", endpoint.RecordTypeA, "1.2.3.4"),
+			})
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxWarningsBodySize limits how much of a response to POST /records is read to find warnings and record IDs
+const maxWarningsBodySize = 1 << 20
+
+// applyWarning is a caveat reported by the webhook about a change it applied
+type applyWarning struct {
+	DNSName string `json:"dnsName"`
+	Message string `json:"message"`
+}
+
+// isApplied returns true if status reports that POST /records applied the changes: any 2xx status code
+// but 207, which reports the endpoints rejected without applying any change
+func isApplied(status int) bool {
+	return status >= 200 && status < 300 && status != http.StatusMultiStatus
+}
+
+// applyResponse is the body of a successful response to POST /records
+type applyResponse struct {
+	Warnings  []applyWarning    `json:"warnings"`
+	RecordIDs map[string]string `json:"recordIds"`
+}
+
+// decodeApplyResponse decodes the body of a successful response to POST /records.
+// 204 responses and empty bodies result in an empty applyResponse.
+func decodeApplyResponse(resp *http.Response) applyResponse {
+	var body applyResponse
+	if !isApplied(resp.StatusCode) || resp.StatusCode == http.StatusNoContent {
+		return body
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWarningsBodySize)).Decode(&body); err != nil {
+		if err != io.EOF {
+			log.Debugf("Failed to decode response to applied changes: %s", err.Error())
+		}
+		return applyResponse{}
+	}
+	return body
+}
+
+// logWarnings logs the warnings reported by the webhook about the applied changes
+func (r applyResponse) logWarnings() {
+	for _, w := range r.Warnings {
+		log.WithField("dnsName", w.DNSName).Warnf("Webhook applied change with warning: %s", w.Message)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestApplyWarnings(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		body     string
+		warnings []string
+	}{
+		{name: "no content", status: http.StatusNoContent},
+		{name: "empty body", status: http.StatusOK},
+		{name: "no warnings", status: http.StatusOK, body: `{}`},
+		{name: "invalid body", status: http.StatusOK, body: `not json`},
+		{name: "created", status: http.StatusCreated},
+		{name: "accepted", status: http.StatusAccepted},
+		{
+			name:     "created with warnings",
+			status:   http.StatusCreated,
+			body:     `{"warnings": [{"dnsName": "a.example.com", "message": "TTL rounded up to 60"}]}`,
+			warnings: []string{"a.example.com: TTL rounded up to 60"},
+		},
+		{
+			name:     "accepted with warnings",
+			status:   http.StatusAccepted,
+			body:     `{"warnings": [{"dnsName": "a.example.com", "message": "queued"}]}`,
+			warnings: []string{"a.example.com: queued"},
+		},
+		{
+			name:     "warnings",
+			status:   http.StatusOK,
+			body:     `{"warnings": [{"dnsName": "a.example.com", "message": "TTL rounded up to 60"}, {"dnsName": "b.example.com", "message": "target normalized"}]}`,
+			warnings: []string{"a.example.com: TTL rounded up to 60", "b.example.com: target normalized"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL)
+			require.NoError(t, err)
+
+			hook := logtest.NewGlobal()
+			defer hook.Reset()
+			err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			})
+			require.NoError(t, err)
+
+			var warnings []string
+			for _, e := range hook.AllEntries() {
+				if e.Level == log.WarnLevel {
+					warnings = append(warnings, e.Data["dnsName"].(string)+": "+strings.TrimPrefix(e.Message, "Webhook applied change with warning: "))
+				}
+			}
+			require.Equal(t, tc.warnings, warnings)
+		})
+	}
+}
+
+func TestIsApplied(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent} {
+		require.True(t, isApplied(status), status)
+	}
+	for _, status := range []int{http.StatusMultiStatus, http.StatusNotModified, http.StatusBadRequest, http.StatusInternalServerError} {
+		require.False(t, isApplied(status), status)
+	}
+}
@@ -19,10 +19,16 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sync/atomic"
+	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -33,7 +39,7 @@ import (
 )
 
 const (
-	mediaTypeFormatAndVersion = "application/external.dns.webhook+json;version=1"
+	mediaTypeFormatAndVersion = mediaType + ";version=" + defaultVersion
 	contentTypeHeader         = "Content-Type"
 	acceptHeader              = "Accept"
 	maxRetries                = 5
@@ -56,6 +62,24 @@ var (
 			Help:      "Errors with ApplyChanges method",
 		},
 	)
+	recordsUnchangedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "records_unchanged",
+			Help:      "Records calls returning the same records as the previous call, when the no-op fast path is enabled",
+		},
+	)
+	applyChangesBodySize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "applychanges_body_size_bytes",
+			Help:      "Size of the ApplyChanges request bodies, by operations contained in the changes",
+			Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+		},
+		[]string{"operations"},
+	)
 	adjustEndpointsErrorsGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: "external_dns",
@@ -64,37 +88,287 @@ var (
 			Help:      "Errors with AdjustEndpoints method",
 		},
 	)
+	recordsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "records_total",
+			Help:      "Calls of the Records method, by result",
+		},
+		[]string{"result"},
+	)
+	applyChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "applychanges_total",
+			Help:      "Calls of the ApplyChanges method, by result",
+		},
+		[]string{"result"},
+	)
+	applyChangesPlanSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "applychanges_plan_size",
+			Help:      "Number of endpoints of the last ApplyChanges call, by operation",
+		},
+		[]string{"operation"},
+	)
+	adjustEndpointsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "webhook_provider",
+			Name:      "adjustendpoints_total",
+			Help:      "Calls of the AdjustEndpoints method, by result",
+		},
+		[]string{"result"},
+	)
 )
 
 type WebhookProvider struct {
-	client          *http.Client
-	remoteServerURL *url.URL
-	DomainFilter    endpoint.DomainFilter
+	client            *http.Client
+	transport         *http.Transport
+	dialer            *net.Dialer
+	remoteServerURL   *url.URL
+	DomainFilter      endpoint.DomainFilter
+	readOnly          bool
+	labelHeaders      map[string]string
+	adjustTimeout     time.Duration
+	maxEndpoints      int
+	notFoundAsEmpty   bool
+	logUpdateDiffs    bool
+	applyOrder        []ApplyOperation
+	errorIsolation    bool
+	applyMethod       string
+	knownRecords      *recordsSnapshot
+	protectedRecords  []string
+	preview           bool
+	compression       *requestCompression
+	weightValidation  *weightValidation
+	payloadOrder      []ApplyOperation
+	adjustCountCheck  bool
+	clock             Clock
+	decodeRetry       bool
+	ownerFilter       string
+	pendingDeletes    *pendingDeletes
+	errorLog          *errorLog
+	batching          *batching
+	contextHeaders    map[string]interface{}
+	zeroTTL           ZeroTTLMode
+	planReview        *planReview
+	foreignRecords    *foreignRecords
+	labelValidation   *labelValidation
+	tombstones        *tombstones
+	recordIDs         *recordIDCache
+	resourceProperty  bool
+	envelope          *Envelope
+	consistency       *consistencyCheck
+	noopCache         *responseCache
+	signer            *payloadSigner
+	maxTargets        map[string]int
+	fieldNaming       FieldNaming
+	capabilities      capabilities
+	transactions      bool
+	zoneConcurrency   int
+	unmanagedMarker   *marker
+	statusWriter      StatusWriter
+	codecs            map[string]Codec
+	versions          []string
+	version           string
+	sinks             []Sink
+	defaultTTL        endpoint.TTL
+	zoneTTLs          map[string]endpoint.TTL
+	propagation       *propagationWait
+	ttlPolicy         *ttlPolicy
+	apexCNAME         ApexCNAMEMode
+	maxDeletes        int
+	maxDeletesPercent float64
+	decodeLimits      *decodeLimits
+	enrichers         []Enricher
+	authenticator     Authenticator
+	maxRetries        int
+	retriedOperations map[RetryOperation]bool
+	budget            *reconcileBudget
+	// endpointTransforms are applied to a copy of every endpoint sent by ApplyChanges
+	endpointTransforms []func(*endpoint.Endpoint)
+	// zoneScopedRecords fetches the records of recordZones, or of the domain filter, one zone at a time
+	zoneScopedRecords bool
+	recordZones       []string
+	// unknownRecordTypes sets how the records of unknown types returned by Records are handled
+	unknownRecordTypes UnknownRecordTypePolicy
+	// missingTargets sets how the records without targets returned by Records are handled
+	missingTargets MissingTargetsPolicy
+	// domainFilterPolicy sets how the endpoints outside of DomainFilter are reported
+	domainFilterPolicy DomainFilterPolicy
+	// syncIncomplete is set when the last Records response reported an incomplete sync
+	syncIncomplete *atomic.Bool
+	// recordsCount is the number of endpoints within the domain filter returned by the last Records call
+	recordsCount *atomic.Int64
+	// recordTypePriority ranks the record types kept when a CNAME conflicts with other types
+	recordTypePriority map[string]int
+	// coalescer deduplicates concurrent identical calls to ApplyChanges
+	coalescer *applyCoalescer
+	// stripTrailingDots removes the trailing dot of the DNS names returned by Records
+	stripTrailingDots bool
+	// deduplicatedTargets removes the duplicate targets of the adjusted endpoints too
+	deduplicatedTargets bool
+	// providerSpecificAllowlist holds the names of the provider specific properties sent by ApplyChanges
+	providerSpecificAllowlist map[string]bool
+	// propertyComparators compare the values of provider specific properties in the plan
+	propertyComparators map[string]plan.PropertyComparator
+	// regexDomainFilter replaces the domain filter negotiated with the webhook
+	regexDomainFilter *endpoint.DomainFilter
+	// negotiationTimeout bounds the negotiation with the webhook in NewWebhookProvider
+	negotiationTimeout time.Duration
+	// statusClasses override how the failures with some status codes are handled
+	statusClasses map[int]StatusClass
+}
+
+// WebhookOption allows to extend the webhook provider
+type WebhookOption func(*WebhookProvider)
+
+// WebhookWithReadOnly makes ApplyChanges only log the intended changes without contacting the webhook
+func WebhookWithReadOnly() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.readOnly = true
+	}
 }
 
 func init() {
+	prometheus.MustRegister(recordsUnchangedCounter)
 	prometheus.MustRegister(recordsErrorsGauge)
 	prometheus.MustRegister(applyChangesErrorsGauge)
 	prometheus.MustRegister(adjustEndpointsErrorsGauge)
+	prometheus.MustRegister(applyChangesBodySize)
+	prometheus.MustRegister(recordsTotal)
+	prometheus.MustRegister(applyChangesTotal)
+	prometheus.MustRegister(applyChangesPlanSize)
+	prometheus.MustRegister(adjustEndpointsTotal)
+}
+
+// countResult increments the counter of the result of a call, success or error
+func countResult(counter *prometheus.CounterVec, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	counter.WithLabelValues(result).Inc()
+}
+
+// observePlanSize sets the plan size gauges to the number of endpoints of every operation of changes
+func observePlanSize(changes *plan.Changes) {
+	var creates, updates, deletes int
+	if changes != nil {
+		creates, updates, deletes = len(changes.Create), len(changes.UpdateNew), len(changes.Delete)
+	}
+	applyChangesPlanSize.WithLabelValues(string(ApplyOperationCreate)).Set(float64(creates))
+	applyChangesPlanSize.WithLabelValues(string(ApplyOperationUpdate)).Set(float64(updates))
+	applyChangesPlanSize.WithLabelValues(string(ApplyOperationDelete)).Set(float64(deletes))
+}
+
+// WebhookWithLabelHeaders sends the value of the given endpoint labels as request headers on ApplyChanges.
+// The map is keyed by label key and the value is the name of the header to set.
+// A header is only set when every endpoint in the change set carries the same value for the label,
+// it is omitted when the batch mixes values or some endpoints lack the label.
+func WebhookWithLabelHeaders(labelHeaders map[string]string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.labelHeaders = labelHeaders
+	}
+}
+
+// WebhookWithAdjustEndpointsTimeout bounds the duration of the AdjustEndpoints call.
+// When the timeout expires, the endpoints are returned unchanged instead of failing the reconciliation.
+func WebhookWithAdjustEndpointsTimeout(timeout time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.adjustTimeout = timeout
+	}
+}
+
+// WebhookWithAdjustEndpointsCountCheck makes AdjustEndpoints fail when the webhook returns a different number
+// of endpoints than it was sent, since dropped endpoints would otherwise silently vanish from the plan
+func WebhookWithAdjustEndpointsCountCheck() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.adjustCountCheck = true
+	}
 }
 
-func NewWebhookProvider(u string) (*WebhookProvider, error) {
+// WebhookWithMaxEndpoints makes ApplyChanges fail when the changes contain more than max endpoints.
+// A value of zero, the default, means unlimited.
+func WebhookWithMaxEndpoints(max int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.maxEndpoints = max
+	}
+}
+
+// WebhookWithNotFoundAsEmptyRecords handles a 404 response to GET /records as no records,
+// for webhooks returning 404 rather than an empty list when they have no records.
+// A warning is logged since 404 can also be caused by a misconfigured URL.
+func WebhookWithNotFoundAsEmptyRecords() WebhookOption {
+	return func(p *WebhookProvider) {
+		p.notFoundAsEmpty = true
+	}
+}
+
+// WebhookWithNegotiationTimeout bounds the negotiation with the webhook when the provider is created,
+// retries included, so that an unresponsive or misconfigured webhook fails the startup quickly.
+func WebhookWithNegotiationTimeout(timeout time.Duration) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.negotiationTimeout = timeout
+	}
+}
+
+func NewWebhookProvider(u string, opts ...WebhookOption) (*WebhookProvider, error) {
 	parsedURL, err := url.Parse(u)
 	if err != nil {
 		return nil, err
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := newDialer()
+	transport.DialContext = dialer.DialContext
+	p := &WebhookProvider{
+		client:          &http.Client{Transport: transport},
+		transport:       transport,
+		dialer:          dialer,
+		versions:        []string{defaultVersion},
+		syncIncomplete:  &atomic.Bool{},
+		recordsCount:    &atomic.Int64{},
+		recordIDs:       newRecordIDCache(),
+		remoteServerURL: parsedURL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.applyMethod != "" && p.applyMethod != http.MethodPost && p.applyMethod != http.MethodPut {
+		return nil, fmt.Errorf("unsupported apply method %q", p.applyMethod)
+	}
+	if err := p.validateProtectedRecords(); err != nil {
+		return nil, err
+	}
+	if err := p.propagation.validate(); err != nil {
+		return nil, err
+	}
+
 	// negotiate API information
-	req, err := http.NewRequest("GET", u, nil)
+	ctx := context.Background()
+	if p.negotiationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.negotiationTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set(acceptHeader, mediaTypeFormatAndVersion)
+	req.Header.Set(acceptHeader, p.accept())
+	if err := p.authenticate(req); err != nil {
+		return nil, err
+	}
 
-	client := &http.Client{}
 	var resp *http.Response
 	err = backoff.Retry(func() error {
-		resp, err = client.Do(req)
+		resp, err = p.client.Do(req)
 		if err != nil {
 			log.Debugf("Failed to connect to plugin api: %v", err)
 			return err
@@ -104,9 +378,12 @@ func NewWebhookProvider(u string) (*WebhookProvider, error) {
 			return backoff.Permanent(fmt.Errorf("status code < 500"))
 		}
 		return nil
-	}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries))
+	}, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx))
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to connect to plugin api: no response within the negotiation timeout of %s: %v", p.negotiationTimeout, err)
+		}
 		return nil, fmt.Errorf("failed to connect to plugin api: %v", err)
 	}
 
@@ -115,76 +392,391 @@ func NewWebhookProvider(u string) (*WebhookProvider, error) {
 	// read the serialized DomainFilter from the response body and set it in the webhook provider struct
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body of DomainFilter: %v", err)
+	}
+
 	df := endpoint.DomainFilter{}
-	if err := json.NewDecoder(resp.Body).Decode(&df); err != nil {
+	if err := json.Unmarshal(body, &df); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response body of DomainFilter: %v", err)
 	}
 
-	if contentType != mediaTypeFormatAndVersion {
+	negotiated := negotiationResponse{}
+	if err := json.Unmarshal(body, &negotiated); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal capabilities: %v", err)
+	}
+
+	version, err := versionOf(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("wrong content type returned from server: %s", contentType)
+	}
+	if _, ok := p.codecForVersion(version); !ok {
 		return nil, fmt.Errorf("wrong content type returned from server: %s", contentType)
 	}
+	p.version = version
+	log.Infof("Negotiated version %s of the webhook API with %s", version, p.remoteServerURL.Redacted())
 
-	return &WebhookProvider{
-		client:          client,
-		remoteServerURL: parsedURL,
-		DomainFilter:    df,
-	}, nil
+	p.DomainFilter = p.negotiatedDomainFilter(df)
+	regexFilter := p.regexDomainFilter != nil || negotiated.RegexInclude != "" || negotiated.RegexExclude != ""
+	if err := p.validateZoneDomainFilter(regexFilter); err != nil {
+		return nil, err
+	}
+	p.capabilities = negotiated.Capabilities
+	p.zoneTTLs = zoneDefaultTTLs(negotiated.DefaultTTLs)
+	p.enforceMinTTL(p.capabilities.MinTTL)
+	return p, nil
 }
 
 // Records will make a GET call to remoteServerURL/records and return the results
-func (p WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	u := p.remoteServerURL.JoinPath("records").String()
-	req, err := http.NewRequest("GET", u, nil)
+func (p WebhookProvider) Records(ctx context.Context) (_ []*endpoint.Endpoint, err error) {
+	defer func() {
+		countResult(recordsTotal, err)
+		p.logError("Records", err)
+	}()
+	if p.budget != nil && retryBudgetFromContext(ctx) == nil {
+		ctx = ContextWithRetryBudget(ctx, p.budget.reset())
+	}
+	p.pendingDeletes.reconcileStarted()
+	p.foreignRecords.reset()
+	p.tombstones.reset()
+
+	endpoints, complete, unchanged, err := p.fetchAllRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.syncIncomplete.Store(!complete)
+	if p.noopCache != nil {
+		if unchanged {
+			recordsUnchangedCounter.Inc()
+		} else {
+			p.noopCache.invalidate(adjustCacheKey)
+		}
+	}
+
+	// the records are replaced starting from all the records of the webhook, even those filtered out
+	p.knownRecords.store(endpoints)
+	endpoints, err = p.normalizeRecords(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	p.storeRecordsCount(endpoints)
+	p.consistency.verify(endpoints)
+	return endpoints, nil
+}
+
+// normalizeRecords filters and completes the endpoints returned by the webhook
+func (p WebhookProvider) normalizeRecords(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	endpoints, err := p.filterUnknownRecordTypes(endpoints)
 	if err != nil {
 		recordsErrorsGauge.Inc()
-		log.Debugf("Failed to create request: %s", err.Error())
 		return nil, err
 	}
-	req.Header.Set(acceptHeader, mediaTypeFormatAndVersion)
-	resp, err := p.client.Do(req)
+	endpoints, err = p.filterMissingTargets(endpoints)
 	if err != nil {
 		recordsErrorsGauge.Inc()
-		log.Debugf("Failed to perform request: %s", err.Error())
 		return nil, err
 	}
+
+	if p.unmanagedMarker != nil {
+		endpoints = filterEndpoints(endpoints, p.isManaged)
+	}
+	if p.ownerFilter != "" {
+		endpoints = p.filterOwned(endpoints)
+	}
+	p.setDefaultTTLs(endpoints)
+	if p.resourceProperty {
+		for _, e := range endpoints {
+			e.DeleteProviderSpecificProperty(providerSpecificResource)
+		}
+	}
+	if p.stripTrailingDots {
+		for _, e := range endpoints {
+			e.DNSName = withTrailingDot(e.DNSName, false)
+		}
+	}
+	normalizeAlias(endpoints)
+	p.recordIDs.restore(endpoints)
+	return p.tombstones.filter(endpoints), nil
+}
+
+// fetchAllRecords gets the records of all the zones, and returns whether the webhook reported them
+// as complete and whether they are unchanged since the previous call
+func (p WebhookProvider) fetchAllRecords(ctx context.Context) (endpoints []*endpoint.Endpoint, complete, unchanged bool, err error) {
+	zones := p.recordsZones()
+	if len(zones) == 0 {
+		return p.fetchRecords(ctx, "")
+	}
+	endpoints, complete, unchanged = []*endpoint.Endpoint{}, true, true
+	seen := map[endpoint.EndpointKey]bool{}
+	for _, zone := range zones {
+		zoneEndpoints, zoneComplete, zoneUnchanged, err := p.fetchRecords(ctx, zone)
+		if err != nil {
+			return nil, false, false, err
+		}
+		complete = complete && zoneComplete
+		unchanged = unchanged && zoneUnchanged
+		for _, e := range zoneEndpoints {
+			if !seen[e.Key()] {
+				seen[e.Key()] = true
+				endpoints = append(endpoints, e)
+			}
+		}
+	}
+	return endpoints, complete, unchanged, nil
+}
+
+// fetchRecords gets the records of zone, or all the records if zone is empty,
+// and returns whether the webhook reported them as complete and whether they are unchanged
+// since the previous call when the no-op fast path is enabled
+func (p WebhookProvider) fetchRecords(ctx context.Context, zone string) (endpoints []*endpoint.Endpoint, complete, unchanged bool, err error) {
+	err = p.retryOnDecodeError("records", func() error {
+		endpoints, complete, unchanged, err = p.fetchRecordsOnce(ctx, zone)
+		return err
+	})
+	return endpoints, complete, unchanged, err
+}
+
+func (p WebhookProvider) fetchRecordsOnce(ctx context.Context, zone string) ([]*endpoint.Endpoint, bool, bool, error) {
+	records := p.remoteServerURL.JoinPath("records")
+	query := url.Values{}
+	if zone != "" {
+		query.Set("zone", zone)
+	}
+	records.RawQuery = p.recordsQuery(query)
+	u := records.String()
+	resp, err := p.doWithRetry(ctx, RetryOperationRecords, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(acceptHeader, p.accept())
+		return req, nil
+	})
+	if err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to perform request: %s", err.Error())
+		return nil, false, false, err
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound && p.notFoundAsEmpty {
+		log.Warnf("Webhook returned 404 on %s, assuming there are no records", resp.Request.URL)
+		return []*endpoint.Endpoint{}, true, false, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		recordsErrorsGauge.Inc()
-		log.Debugf("Failed to get records with code %d", resp.StatusCode)
-		return nil, fmt.Errorf("failed to get records with code %d", resp.StatusCode)
+		log.WithFields(log.Fields{"path": resp.Request.URL.Path, "status": resp.StatusCode}).Debug("Failed to get records")
+		return nil, false, false, p.newStatusError(resp.StatusCode, fmt.Sprintf("failed to get records with code %d", resp.StatusCode))
+	}
+
+	codec, err := p.responseCodec(resp.Header.Get(contentTypeHeader))
+	if err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to get records: %s", err.Error())
+		return nil, false, false, err
 	}
 
 	endpoints := []*endpoint.Endpoint{}
-	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+	unchanged, err := p.decodeEndpointsCached("records/"+zone, p.envelope.recordsCodec(codec), resp, &endpoints)
+	if err != nil {
 		recordsErrorsGauge.Inc()
 		log.Debugf("Failed to decode response body: %s", err.Error())
-		return nil, err
+		return nil, false, false, err
 	}
-	return endpoints, nil
+	log.WithFields(log.Fields{"path": resp.Request.URL.Path, "zone": zone, "endpoints": len(endpoints)}).Debug("Received records")
+	return endpoints, syncComplete(resp), unchanged, nil
 }
 
 // ApplyChanges will make a POST to remoteServerURL/records with the changes
-func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
-	u := p.remoteServerURL.JoinPath("records").String()
+func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) (err error) {
+	if p.coalescer != nil {
+		coalescer := p.coalescer
+		p.coalescer = nil
+		return coalescer.do(changes, func() error {
+			return p.ApplyChanges(ctx, changes)
+		})
+	}
+	defer func() {
+		countResult(applyChangesTotal, err)
+		p.logError("ApplyChanges", err)
+	}()
+	observePlanSize(changes)
+	if p.statusWriter != nil {
+		defer func() {
+			p.writeStatus(ctx, changes, err)
+		}()
+	}
 
-	b := new(bytes.Buffer)
-	if err := json.NewEncoder(b).Encode(changes); err != nil {
+	if p.readOnly {
+		logChanges(changes)
+		return nil
+	}
+
+	if p.unmanagedMarker != nil && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = filterChanges(changes, p.isManaged)
+		if hadChanges && !changes.HasChanges() {
+			log.Debugf("All changes are for unmanaged endpoints, nothing to apply")
+			return nil
+		}
+	}
+
+	if len(p.capabilities.RecordTypes) > 0 && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = filterChanges(changes, p.supportsRecordType)
+		if hadChanges && !changes.HasChanges() {
+			return nil
+		}
+	}
+
+	if p.syncIncomplete != nil && p.syncIncomplete.Load() && p.replacesRecords() {
+		applyChangesErrorsGauge.Inc()
+		return errSyncIncomplete
+	}
+	if p.syncIncomplete != nil && p.syncIncomplete.Load() && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = withoutDeletes(changes)
+		if hadChanges && !changes.HasChanges() {
+			return nil
+		}
+	}
+
+	if len(p.protectedRecords) > 0 && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = p.withoutProtectedDeletes(changes)
+		if hadChanges && !changes.HasChanges() {
+			return nil
+		}
+	}
+
+	if p.ownerFilter != "" && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = p.ownedChanges(changes)
+		if hadChanges && !changes.HasChanges() {
+			return nil
+		}
+	}
+
+	if p.pendingDeletes != nil && changes != nil {
+		hadChanges := changes.HasChanges()
+		changes = p.pendingDeletes.withhold(changes, p.clockOrReal().Now())
+		if hadChanges && !changes.HasChanges() {
+			return nil
+		}
+	}
+
+	changes = p.tombstones.translate(changes)
+	changes = p.resolveTypeConflicts(changes)
+
+	if changes != nil {
+		p.recordIDs.restore(changes.UpdateNew)
+	}
+	planned := changes
+	changes = p.prepareChanges(changes)
+	if changes != planned {
+		defer copyRecordIDs(planned, changes)
+	}
+	p.enrichChanges(ctx, changes)
+	p.stripProviderSpecific(changes)
+	if len(p.sinks) > 0 {
+		defer func() {
+			if err == nil {
+				p.mirrorChanges(ctx, changes)
+			}
+		}()
+	}
+	if p.consistency != nil {
+		defer func() {
+			if err == nil {
+				p.consistency.applied(changes)
+			}
+		}()
+	}
+
+	if n := len(changesEndpoints(changes)); p.maxEndpoints > 0 && n > p.maxEndpoints {
+		applyChangesErrorsGauge.Inc()
+		return fmt.Errorf("refusing to apply %d endpoints, exceeds the maximum of %d endpoints per reconcile", n, p.maxEndpoints)
+	}
+
+	if err := p.validateChanges(changes); err != nil {
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to encode changes: %s", err.Error())
 		return err
 	}
 
-	req, err := http.NewRequest("POST", u, b)
+	if p.logUpdateDiffs {
+		logUpdateDiffs(changes)
+	}
+
+	if err := p.planReview.write(changes); err != nil {
+		applyChangesErrorsGauge.Inc()
+		return fmt.Errorf("failed to write the changes for review: %w", err)
+	}
+
+	if p.preview {
+		if err := p.previewChanges(ctx, changes); err != nil {
+			applyChangesErrorsGauge.Inc()
+			return err
+		}
+	}
+
+	if p.transactions {
+		if p.capabilities.Transactions {
+			return p.applyChangesInTransaction(ctx, changes)
+		}
+		log.Debugf("Webhook does not support transactions, applying changes directly")
+	}
+	if p.zoneConcurrency > 0 && changes != nil && !p.replacesRecords() {
+		return p.applyChangesPerZone(ctx, changes)
+	}
+	return p.applyChanges(ctx, changes, nil)
+}
+
+// postChanges sends the changes to the webhook in a single POST to remoteServerURL/records,
+// or the full desired state with PUT, setting the given headers in addition to the default ones
+func (p WebhookProvider) postChanges(ctx context.Context, changes *plan.Changes, extraHeaders map[string]string) error {
+	u := p.remoteServerURL.JoinPath("records").String()
+
+	method := http.MethodPost
+	if p.replacesRecords() {
+		method = http.MethodPut
+	}
+	payload, state, err := p.applyPayload(changes)
 	if err != nil {
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to create request: %s", err.Error())
 		return err
 	}
 
-	req.Header.Set(contentTypeHeader, mediaTypeFormatAndVersion)
+	body, encoding, err := p.compression.compress(payload)
+	if err != nil {
+		applyChangesErrorsGauge.Inc()
+		log.Debugf("Failed to compress changes: %s", err.Error())
+		return err
+	}
+	applyChangesBodySize.WithLabelValues(operationsOf(changes)).Observe(float64(len(body)))
+	headers := uniformLabelHeaders(p.labelHeaders, changes)
+	for header, value := range p.headersFromContext(ctx) {
+		headers[header] = value
+	}
+	resp, err := p.doWithRetry(ctx, RetryOperationApplyChanges, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		p.signer.signRequest(req, body)
 
-	resp, err := p.client.Do(req)
+		req.Header.Set(contentTypeHeader, p.contentType())
+		if encoding != "" {
+			req.Header.Set(contentEncodingHeader, encoding)
+		}
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		for header, value := range extraHeaders {
+			req.Header.Set(header, value)
+		}
+		return req, nil
+	})
 	if err != nil {
 		applyChangesErrorsGauge.Inc()
 		log.Debugf("Failed to perform request: %s", err.Error())
@@ -192,19 +784,177 @@ func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
+	if !isApplied(resp.StatusCode) {
+		class := p.statusClass(resp.StatusCode)
+		if class == StatusClassSkip {
+			log.Warnf("Ignoring the failure of %s with code %d, skipped by the status classes", resp.Request.URL.Path, resp.StatusCode)
+			return nil
+		}
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to apply changes with code %d", resp.StatusCode)
-		return fmt.Errorf("failed to apply changes with code %d", resp.StatusCode)
+		log.WithFields(log.Fields{"path": resp.Request.URL.Path, "status": resp.StatusCode}).Debug("Failed to apply changes")
+		return newApplyStatusError(resp, class)
+	}
+	applied := decodeApplyResponse(resp)
+	applied.logWarnings()
+	p.recordIDs.assign(changes, applied.RecordIDs)
+	fields := log.Fields{"path": resp.Request.URL.Path}
+	if changes != nil {
+		fields["creates"] = len(changes.Create)
+		fields["updates"] = len(changes.UpdateNew)
+		fields["deletes"] = len(changes.Delete)
+	}
+	log.WithFields(fields).Debug("Applied changes")
+	if state != nil {
+		p.knownRecords.store(state)
+	}
+
+	if changeID := resp.Header.Get(changeIDHeader); p.propagation != nil && changeID != "" {
+		if err := p.waitForPropagation(ctx, changeID); err != nil {
+			applyChangesErrorsGauge.Inc()
+			return err
+		}
 	}
 	return nil
 }
 
+// applyPayload encodes the body applying changes, before compression: the changes, or the full desired state
+// returned along when the records are replaced
+func (p WebhookProvider) applyPayload(changes *plan.Changes) ([]byte, []*endpoint.Endpoint, error) {
+	if !p.replacesRecords() {
+		payload, err := p.encodeChanges(changes)
+		return payload, nil, err
+	}
+	state, err := p.knownRecords.desired(changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := p.encodeRecords(state)
+	return payload, state, err
+}
+
+// encodeChanges encodes changes to the body of an ApplyChanges request, before compression
+func (p WebhookProvider) encodeChanges(changes *plan.Changes) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if len(p.payloadOrder) > 0 {
+		if err := p.codec().EncodeChanges(b, sortedChanges(changes)); err != nil {
+			log.Debugf("Failed to encode changes: %s", err.Error())
+			return nil, err
+		}
+		ordered, err := orderPayload(b.Bytes(), p.payloadOrder)
+		if err != nil {
+			log.Debugf("Failed to order changes: %s", err.Error())
+			return nil, err
+		}
+		return p.transformPayload(ordered)
+	}
+	if err := p.codec().EncodeChanges(b, changes); err != nil {
+		log.Debugf("Failed to encode changes: %s", err.Error())
+		return nil, err
+	}
+	return p.transformPayload(b.Bytes())
+}
+
+// encodeRecords encodes the records to the body of a PUT request, before compression
+func (p WebhookProvider) encodeRecords(records []*endpoint.Endpoint) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if err := p.codec().EncodeEndpoints(b, records); err != nil {
+		log.Debugf("Failed to encode records: %s", err.Error())
+		return nil, err
+	}
+	return p.transformPayload(b.Bytes())
+}
+
+// transformPayload adds the zero TTLs to the encoded payload and wraps it in the envelope
+func (p WebhookProvider) transformPayload(payload []byte) ([]byte, error) {
+	if p.zeroTTL == ZeroTTLSend {
+		var err error
+		if payload, err = withZeroTTLs(payload, p.ttlField()); err != nil {
+			log.Debugf("Failed to add zero TTLs: %s", err.Error())
+			return nil, err
+		}
+	}
+	wrapped, err := p.envelope.wrap(payload, p.clockOrReal().Now())
+	if err != nil {
+		log.Debugf("Failed to wrap changes: %s", err.Error())
+		return nil, err
+	}
+	return wrapped, nil
+}
+
+// changesEndpoints returns all the endpoints contained in the changes
+func changesEndpoints(changes *plan.Changes) []*endpoint.Endpoint {
+	if changes == nil {
+		return nil
+	}
+	endpoints := make([]*endpoint.Endpoint, 0, len(changes.Create)+len(changes.UpdateOld)+len(changes.UpdateNew)+len(changes.Delete))
+	endpoints = append(endpoints, changes.Create...)
+	endpoints = append(endpoints, changes.UpdateOld...)
+	endpoints = append(endpoints, changes.UpdateNew...)
+	endpoints = append(endpoints, changes.Delete...)
+	return endpoints
+}
+
+// uniformLabelHeaders returns the headers for the labels that have the same value on every endpoint of the changes
+func uniformLabelHeaders(labelHeaders map[string]string, changes *plan.Changes) map[string]string {
+	headers := map[string]string{}
+	if len(labelHeaders) == 0 {
+		return headers
+	}
+	endpoints := changesEndpoints(changes)
+	if len(endpoints) == 0 {
+		return headers
+	}
+
+	for label, header := range labelHeaders {
+		value, uniform := endpoints[0].Labels[label]
+		for _, e := range endpoints[1:] {
+			if v, ok := e.Labels[label]; !ok || v != value {
+				uniform = false
+				break
+			}
+		}
+		if !uniform {
+			log.Debugf("Endpoints do not share a value for label %s, omitting header %s", label, header)
+			continue
+		}
+		headers[header] = value
+	}
+	return headers
+}
+
+// logChanges logs the changes that would have been applied in read-only mode
+func logChanges(changes *plan.Changes) {
+	if changes == nil {
+		return
+	}
+	for _, v := range changes.Create {
+		log.Infof("Read-only mode, skipping CREATE: %v", v)
+	}
+	for _, v := range changes.UpdateOld {
+		log.Infof("Read-only mode, skipping UPDATE (old): %v", v)
+	}
+	for _, v := range changes.UpdateNew {
+		log.Infof("Read-only mode, skipping UPDATE (new): %v", v)
+	}
+	for _, v := range changes.Delete {
+		log.Infof("Read-only mode, skipping DELETE: %v", v)
+	}
+}
+
 // AdjustEndpoints will call the provider doing a POST on `/adjustendpoints` which will return a list of modified endpoints
 // based on a provider specific requirement.
 // This method returns an empty slice in case there is a technical error on the provider's side so that no endpoints will be considered.
-func (p WebhookProvider) AdjustEndpoints(e []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
-	endpoints := []*endpoint.Endpoint{}
+func (p WebhookProvider) AdjustEndpoints(e []*endpoint.Endpoint) (_ []*endpoint.Endpoint, err error) {
+	defer func() {
+		countResult(adjustEndpointsTotal, err)
+		p.logError("AdjustEndpoints", err)
+	}()
+	if err := p.checkDomainFilter(e); err != nil {
+		adjustEndpointsErrorsGauge.Inc()
+		return nil, err
+	}
+	p.rewriteApexCNAMEs(e)
+
 	u, err := url.JoinPath(p.remoteServerURL.String(), "adjustendpoints")
 	if err != nil {
 		adjustEndpointsErrorsGauge.Inc()
@@ -213,23 +963,90 @@ func (p WebhookProvider) AdjustEndpoints(e []*endpoint.Endpoint) ([]*endpoint.En
 	}
 
 	b := new(bytes.Buffer)
-	if err := json.NewEncoder(b).Encode(e); err != nil {
+	if err := p.codec().EncodeEndpoints(b, e); err != nil {
 		adjustEndpointsErrorsGauge.Inc()
 		log.Debugf("Failed to encode endpoints, %s", err)
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", u, b)
+	ctx := context.Background()
+	if p.adjustTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.adjustTimeout)
+		defer cancel()
+	}
+
+	if budget := p.budget.get(); budget != nil {
+		ctx = ContextWithRetryBudget(ctx, budget)
+	}
+
+	body := b.Bytes()
+	var bodyHash [sha256.Size]byte
+	if p.noopCache != nil {
+		bodyHash = sha256.Sum256(body)
+		if cached, ok := p.noopCache.lookup(adjustCacheKey, bodyHash); ok {
+			log.Debug("Endpoints and records unchanged, reusing the previous AdjustEndpoints result")
+			return cached, nil
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	err = p.retryOnDecodeError("adjustendpoints", func() error {
+		endpoints, err = p.postAdjustEndpoints(ctx, u, body)
+		return err
+	})
 	if err != nil {
-		adjustEndpointsErrorsGauge.Inc()
-		log.Debugf("Failed to create new HTTP request, %s", err)
+		// only the AdjustEndpoints timeout falls back, the other timeouts are failures
+		if p.adjustTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Warnf("AdjustEndpoints did not complete within %s, using endpoints unchanged", p.adjustTimeout)
+			endpoints = copyEndpoints(e)
+			normalizeAlias(endpoints)
+			p.conformEndpoints(endpoints)
+			return endpoints, nil
+		}
 		return nil, err
 	}
 
-	req.Header.Set(contentTypeHeader, mediaTypeFormatAndVersion)
-	req.Header.Set(acceptHeader, mediaTypeFormatAndVersion)
+	if p.adjustCountCheck && len(endpoints) != len(e) {
+		adjustEndpointsErrorsGauge.Inc()
+		return nil, fmt.Errorf("webhook returned %d endpoints from AdjustEndpoints, %d were sent", len(endpoints), len(e))
+	}
+	restorePinnedTTLs(e, endpoints)
+	normalizeAlias(endpoints)
+	p.conformEndpoints(endpoints)
+	if p.noopCache != nil {
+		p.noopCache.store(adjustCacheKey, bodyHash, endpoints)
+	}
+	return endpoints, nil
+}
+
+// conformEndpoints makes to the adjusted endpoints the changes ApplyChanges makes to the endpoints it sends,
+// so that the desired endpoints match the records and are not updated again at every reconciliation
+func (p WebhookProvider) conformEndpoints(endpoints []*endpoint.Endpoint) {
+	for _, e := range endpoints {
+		p.ttlPolicy.clamp(e)
+		if p.deduplicatedTargets {
+			deduplicateTargets(e)
+		}
+		if p.providerSpecificAllowlist != nil {
+			p.stripEndpointProviderSpecific(e)
+		}
+	}
+}
 
-	resp, err := p.client.Do(req)
+// postAdjustEndpoints sends the encoded endpoints to u and decodes the adjusted endpoints of the response
+func (p WebhookProvider) postAdjustEndpoints(ctx context.Context, u string, body []byte) ([]*endpoint.Endpoint, error) {
+	resp, err := p.doWithRetry(ctx, RetryOperationAdjustEndpoints, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		p.signer.signRequest(req, body)
+
+		req.Header.Set(contentTypeHeader, p.contentType())
+		req.Header.Set(acceptHeader, p.accept())
+		return req, nil
+	})
 	if err != nil {
 		adjustEndpointsErrorsGauge.Inc()
 		log.Debugf("Failed executing http request, %s", err)
@@ -239,16 +1056,25 @@ func (p WebhookProvider) AdjustEndpoints(e []*endpoint.Endpoint) ([]*endpoint.En
 
 	if resp.StatusCode != http.StatusOK {
 		adjustEndpointsErrorsGauge.Inc()
-		log.Debugf("Failed to AdjustEndpoints with code %d", resp.StatusCode)
-		return nil, fmt.Errorf("failed to AdjustEndpoints with code %d", resp.StatusCode)
+		log.WithFields(log.Fields{"path": resp.Request.URL.Path, "status": resp.StatusCode}).Debug("Failed to AdjustEndpoints")
+		return nil, p.newStatusError(resp.StatusCode, fmt.Sprintf("failed to AdjustEndpoints with code %d", resp.StatusCode))
+	}
+
+	codec, err := p.responseCodec(resp.Header.Get(contentTypeHeader))
+	if err != nil {
+		adjustEndpointsErrorsGauge.Inc()
+		log.Debugf("Failed to AdjustEndpoints: %s", err.Error())
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+	endpoints := []*endpoint.Endpoint{}
+	if err := p.decodeEndpoints(codec, resp, &endpoints); err != nil {
 		recordsErrorsGauge.Inc()
 		log.Debugf("Failed to decode response body: %s", err.Error())
 		return nil, err
 	}
 
+	log.WithFields(log.Fields{"path": resp.Request.URL.Path, "endpoints": len(endpoints)}).Debug("Adjusted endpoints")
 	return endpoints, nil
 }
 
@@ -23,9 +23,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
 )
 
 func TestInvalidDomainFilter(t *testing.T) {
@@ -212,3 +218,662 @@ func TestAdjustendpointsWithError(t *testing.T) {
 	_, err = provider.AdjustEndpoints(endpoints)
 	require.Error(t, err)
 }
+
+func TestApplyChangesReadOnly(t *testing.T) {
+	posted := false
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		if r.Method == http.MethodPost {
+			posted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[{
+			"dnsName" : "test.example.com"
+		}]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithReadOnly())
+	require.NoError(t, err)
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []*endpoint.Endpoint{{
+		DNSName: "test.example.com",
+	}}, endpoints)
+
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{{DNSName: "new.example.com", RecordType: "A", Targets: endpoint.Targets{"1.2.3.4"}}},
+		Delete: endpoints,
+	})
+	require.NoError(t, err)
+	require.False(t, posted)
+}
+
+func TestApplyChangesLabelHeaders(t *testing.T) {
+	var headers http.Header
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		headers = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithLabelHeaders(map[string]string{
+		endpoint.OwnerLabelKey:    "X-Owner",
+		endpoint.ResourceLabelKey: "X-Resource",
+	}))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		changes  *plan.Changes
+		owner    string
+		resource string
+	}{
+		{
+			name: "uniform batch",
+			changes: &plan.Changes{
+				Create: []*endpoint.Endpoint{{DNSName: "a.example.com", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/a"}}},
+				Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/a"}}},
+			},
+			owner:    "owner",
+			resource: "ingress/default/a",
+		},
+		{
+			name: "mixed batch",
+			changes: &plan.Changes{
+				Create: []*endpoint.Endpoint{{DNSName: "a.example.com", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/a"}}},
+				Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/b"}}},
+			},
+			owner: "owner",
+		},
+		{
+			name: "missing label",
+			changes: &plan.Changes{
+				Create: []*endpoint.Endpoint{{DNSName: "a.example.com", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}}},
+				Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", Labels: endpoint.Labels{}}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, provider.ApplyChanges(context.TODO(), tc.changes))
+			require.Equal(t, tc.owner, headers.Get("X-Owner"))
+			require.Equal(t, tc.resource, headers.Get("X-Resource"))
+		})
+	}
+}
+
+func TestAdjustEndpointsCountCheck(t *testing.T) {
+	svr := newPayloadServer(`[{"dnsName": "a.example.com", "recordType": "A", "targets": ["1.2.3.4"]}]`)
+	defer svr.Close()
+
+	sent := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}
+
+	// by default, the dropped endpoint vanishes
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	adjusted, err := provider.AdjustEndpoints(sent)
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithAdjustEndpointsCountCheck())
+	require.NoError(t, err)
+	_, err = provider.AdjustEndpoints(sent)
+	require.EqualError(t, err, "webhook returned 1 endpoints from AdjustEndpoints, 2 were sent")
+	adjusted, err = provider.AdjustEndpoints(sent[:1])
+	require.NoError(t, err)
+	require.Len(t, adjusted, 1)
+}
+
+func TestAdjustEndpointsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/adjustendpoints", r.URL.Path)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+	defer close(done)
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithAdjustEndpointsTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	endpoints := []*endpoint.Endpoint{
+		{
+			DNSName:    "test.example.com",
+			RecordTTL:  10,
+			RecordType: "A",
+			Targets: endpoint.Targets{
+				"1.2.3.4",
+			},
+		},
+	}
+	start := time.Now()
+	adjustedEndpoints, err := provider.AdjustEndpoints(endpoints)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, endpoints, adjustedEndpoints)
+
+	// the fallback normalizes copies of the endpoints
+	alias := []*endpoint.Endpoint{endpoint.NewEndpoint("alias.example.com", "alias", "target.example.org")}
+	adjustedEndpoints, err = provider.AdjustEndpoints(alias)
+	require.NoError(t, err)
+	require.Equal(t, "ALIAS", adjustedEndpoints[0].RecordType)
+	require.Equal(t, "alias", alias[0].RecordType)
+	require.Empty(t, alias[0].ProviderSpecific)
+}
+
+func TestAdjustEndpointsRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+	defer close(done)
+
+	// without an AdjustEndpoints timeout, the request timeout fails the call
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithRequestTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	adjustedEndpoints, err := provider.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint("test.example.com", endpoint.RecordTypeA, "1.2.3.4")})
+	require.Error(t, err)
+	require.Nil(t, adjustedEndpoints)
+}
+
+func TestNegotiationTimeout(t *testing.T) {
+	done := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer svr.Close()
+	defer close(done)
+
+	start := time.Now()
+	_, err := NewWebhookProvider(svr.URL, WebhookWithNegotiationTimeout(100*time.Millisecond))
+	require.ErrorContains(t, err, "failed to connect to plugin api: no response within the negotiation timeout of 100ms")
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestCAARecordsRoundTrip(t *testing.T) {
+	caa := &endpoint.Endpoint{
+		DNSName:    "example.com",
+		RecordType: endpoint.RecordTypeCAA,
+		RecordTTL:  300,
+		Targets: endpoint.Targets{
+			`0 issue "letsencrypt.org"`,
+			`0 issuewild ";"`,
+			`128 iodef "mailto:security+caa@example.com"`,
+		},
+	}
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&applied))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+		json.NewEncoder(w).Encode([]*endpoint.Endpoint{caa})
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []*endpoint.Endpoint{caa}, endpoints)
+
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{Create: endpoints})
+	require.NoError(t, err)
+	require.Equal(t, []*endpoint.Endpoint{caa}, applied.Create)
+}
+
+func TestApplyChangesMaxEndpoints(t *testing.T) {
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithMaxEndpoints(2))
+	require.NoError(t, err)
+
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{{DNSName: "a.example.com"}},
+		Delete: []*endpoint.Endpoint{{DNSName: "b.example.com"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+
+	err = provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{{DNSName: "a.example.com"}},
+		Delete: []*endpoint.Endpoint{{DNSName: "b.example.com"}, {DNSName: "c.example.com"}},
+	})
+	require.EqualError(t, err, "refusing to apply 3 endpoints, exceeds the maximum of 2 endpoints per reconcile")
+	require.Equal(t, 1, requests)
+}
+
+func findEntry(hook *logtest.Hook, message string) *log.Entry {
+	for _, e := range hook.AllEntries() {
+		if e.Message == message {
+			return e
+		}
+	}
+	return nil
+}
+
+func TestStructuredLogging(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	level := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	defer log.SetLevel(level)
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]}]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	_, err = provider.Records(context.TODO())
+	require.NoError(t, err)
+
+	entry := findEntry(hook, "Webhook request completed")
+	require.NotNil(t, entry)
+	require.Equal(t, http.MethodGet, entry.Data["method"])
+	require.Equal(t, "/records", entry.Data["path"])
+	require.Equal(t, http.StatusOK, entry.Data["status"])
+	require.Contains(t, entry.Data, "duration")
+
+	entry = findEntry(hook, "Received records")
+	require.NotNil(t, entry)
+	require.Equal(t, 1, entry.Data["endpoints"])
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{{DNSName: "a.example.com", RecordType: endpoint.RecordTypeA}},
+		Delete: []*endpoint.Endpoint{{DNSName: "b.example.com", RecordType: endpoint.RecordTypeA}, {DNSName: "c.example.com", RecordType: endpoint.RecordTypeA}},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+
+	entry = findEntry(hook, "Applied changes")
+	require.NotNil(t, entry)
+	require.Equal(t, "/records", entry.Data["path"])
+	require.Equal(t, 1, entry.Data["creates"])
+	require.Equal(t, 0, entry.Data["updates"])
+	require.Equal(t, 2, entry.Data["deletes"])
+}
+
+func TestAdjustEndpointsMetadataIsApplied(t *testing.T) {
+	var applied plan.Changes
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/adjustendpoints":
+			var endpoints []*endpoint.Endpoint
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&endpoints))
+			for _, e := range endpoints {
+				e.Labels = endpoint.Labels{"webhook/record-id": "id-" + e.DNSName}
+				e.SetProviderSpecificProperty("webhook/zone-id", "zone-1")
+			}
+			json.NewEncoder(w).Encode(endpoints)
+		case "/records":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&applied))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+	}
+	desired, err := provider.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+	})
+	require.NoError(t, err)
+
+	p := &plan.Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+	}
+	require.NoError(t, provider.ApplyChanges(context.TODO(), p.Calculate().Changes))
+
+	require.Len(t, applied.Create, 1)
+	require.Equal(t, "id-a.example.com", applied.Create[0].Labels["webhook/record-id"])
+	require.Equal(t, endpoint.ProviderSpecific{{Name: "webhook/zone-id", Value: "zone-1"}}, applied.Create[0].ProviderSpecific)
+	require.Len(t, applied.UpdateNew, 1)
+	require.Equal(t, "id-b.example.com", applied.UpdateNew[0].Labels["webhook/record-id"])
+	require.Equal(t, endpoint.ProviderSpecific{{Name: "webhook/zone-id", Value: "zone-1"}}, applied.UpdateNew[0].ProviderSpecific)
+}
+
+// The webhook API has no /propertyvaluesequal endpoint: provider specific properties
+// are always compared locally by the plan, so no request is made to compare them.
+func TestProviderSpecificComparedLocally(t *testing.T) {
+	var paths []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+		case "/adjustendpoints":
+			io.Copy(w, r.Body)
+		case "/records":
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"],"providerSpecific":[{"name":"weight","value":"10"}]}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	current, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	desired, err := provider.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4").WithProviderSpecific("weight", "20"),
+	})
+	require.NoError(t, err)
+
+	p := &plan.Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords: []string{endpoint.RecordTypeA},
+	}
+	changes := p.Calculate().Changes
+	require.Len(t, changes.UpdateNew, 1)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), changes))
+	require.Equal(t, []string{"/", "/records", "/adjustendpoints", "/records"}, paths)
+}
+
+func TestRecordsNotFoundAsEmpty(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	_, err = provider.Records(context.TODO())
+	require.EqualError(t, err, "failed to get records with code 404")
+
+	provider, err = NewWebhookProvider(svr.URL, WebhookWithNotFoundAsEmptyRecords())
+	require.NoError(t, err)
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.NotNil(t, endpoints)
+	require.Empty(t, endpoints)
+}
+
+// bodySizeSamples returns the count and the sum of the ApplyChanges body sizes observed for operations
+func bodySizeSamples(t *testing.T, operations string) (uint64, float64) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "external_dns_webhook_provider_applychanges_body_size_bytes" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if m.GetLabel()[0].GetValue() == operations {
+				return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	return 0, 0
+}
+
+func TestApplyChangesBodySizeMetric(t *testing.T) {
+	var size int
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		size = len(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	count, sum := bodySizeSamples(t, "create+delete")
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: staticEndpoints(50),
+		Delete: staticEndpoints(10),
+	}))
+	require.Greater(t, size, 1000)
+	newCount, newSum := bodySizeSamples(t, "create+delete")
+	require.Equal(t, count+1, newCount)
+	require.Equal(t, sum+float64(size), newSum)
+}
+
+func TestApplyChangesPlanSizeMetric(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.1.1.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "2.2.2.2")},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("e.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("f.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}))
+	require.Equal(t, 2.0, testutil.ToFloat64(applyChangesPlanSize.WithLabelValues("create")))
+	require.Equal(t, 1.0, testutil.ToFloat64(applyChangesPlanSize.WithLabelValues("update")))
+	require.Equal(t, 3.0, testutil.ToFloat64(applyChangesPlanSize.WithLabelValues("delete")))
+
+	// the gauges reflect the last call
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{}))
+	require.Equal(t, 0.0, testutil.ToFloat64(applyChangesPlanSize.WithLabelValues("delete")))
+}
+
+func TestPTRRecords(t *testing.T) {
+	ipv4 := "4.3.2.1.in-addr.arpa"
+	ipv6 := "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"
+	var stored []*endpoint.Endpoint
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"include": ["1.in-addr.arpa", "8.b.d.0.1.0.0.2.ip6.arpa"]}`))
+			return
+		}
+		if r.URL.Path == "/adjustendpoints" {
+			io.Copy(w, r.Body)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var changes plan.Changes
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+			stored = changes.Create
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithDomainFilterPolicy(DomainFilterPolicyError))
+	require.NoError(t, err)
+
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint(ipv4, endpoint.RecordTypePTR, "host.example.com"),
+		endpoint.NewEndpoint(ipv6, endpoint.RecordTypePTR, "host.example.com"),
+	}
+	// the reverse zones are matched by the domain filter
+	adjusted, err := provider.AdjustEndpoints(desired)
+	require.NoError(t, err)
+	_, err = provider.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint("4.3.2.2.in-addr.arpa", endpoint.RecordTypePTR, "host.example.com")})
+	require.EqualError(t, err, "endpoint 4.3.2.2.in-addr.arpa is not covered by the domain filter of the webhook")
+
+	require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{Create: adjusted}))
+	require.Len(t, stored, 2)
+	require.Equal(t, ipv4, stored[0].DNSName)
+	require.Equal(t, ipv6, stored[1].DNSName)
+
+	records, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for i, e := range records {
+		require.Equal(t, desired[i].DNSName, e.DNSName)
+		require.Equal(t, endpoint.RecordTypePTR, e.RecordType)
+		require.Equal(t, endpoint.Targets{"host.example.com"}, e.Targets)
+	}
+
+	// the round trip plans no changes
+	calculated := (&plan.Plan{
+		Policies:       []plan.Policy{&plan.SyncPolicy{}},
+		Current:        records,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypePTR},
+	}).Calculate()
+	require.False(t, calculated.Changes.HasChanges())
+}
+
+func TestResultCounters(t *testing.T) {
+	failing := false
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch {
+		case r.URL.Path == "/adjustendpoints":
+			io.Copy(w, r.Body)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+
+	calls := map[*prometheus.CounterVec]func() error{
+		recordsTotal: func() error {
+			_, err := provider.Records(context.TODO())
+			return err
+		},
+		applyChangesTotal: func() error {
+			return provider.ApplyChanges(context.TODO(), &plan.Changes{})
+		},
+		adjustEndpointsTotal: func() error {
+			_, err := provider.AdjustEndpoints([]*endpoint.Endpoint{})
+			return err
+		},
+	}
+	for counter, call := range calls {
+		successes := testutil.ToFloat64(counter.WithLabelValues("success"))
+		errors := testutil.ToFloat64(counter.WithLabelValues("error"))
+
+		failing = false
+		require.NoError(t, call())
+		require.Equal(t, successes+1, testutil.ToFloat64(counter.WithLabelValues("success")))
+		require.Equal(t, errors, testutil.ToFloat64(counter.WithLabelValues("error")))
+
+		failing = true
+		require.Error(t, call())
+		require.Equal(t, successes+1, testutil.ToFloat64(counter.WithLabelValues("success")))
+		require.Equal(t, errors+1, testutil.ToFloat64(counter.WithLabelValues("error")))
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ZeroTTLMode defines what ApplyChanges sends for the endpoints without TTL, whose TTL is zero
+type ZeroTTLMode string
+
+const (
+	// ZeroTTLOmit omits the TTL, so that the webhook applies its own default. This is the default,
+	// unless a default TTL is configured or advertised by the webhook.
+	ZeroTTLOmit ZeroTTLMode = "omit"
+	// ZeroTTLDefault sends the default TTL configured or advertised by the webhook, omitting the TTL without one
+	ZeroTTLDefault ZeroTTLMode = "default"
+	// ZeroTTLSend sends a TTL of 0, for webhooks expecting the TTL of every endpoint
+	ZeroTTLSend ZeroTTLMode = "zero"
+)
+
+// ParseZeroTTLMode parses the name of a ZeroTTLMode
+func ParseZeroTTLMode(s string) (ZeroTTLMode, error) {
+	switch mode := ZeroTTLMode(s); mode {
+	case ZeroTTLOmit, ZeroTTLDefault, ZeroTTLSend:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown zero TTL mode %q", s)
+}
+
+// WebhookWithZeroTTL sets what ApplyChanges sends for the endpoints without TTL. The endpoints returned
+// by Records still get the default TTLs, if any, so that they compare equal to the desired endpoints.
+func WebhookWithZeroTTL(mode ZeroTTLMode) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.zeroTTL = mode
+	}
+}
+
+// sendsDefaultTTLs returns true if ApplyChanges sets the default TTLs on the endpoints without TTL
+func (p WebhookProvider) sendsDefaultTTLs() bool {
+	return p.hasDefaultTTLs() && p.zeroTTL != ZeroTTLOmit && p.zeroTTL != ZeroTTLSend
+}
+
+// ttlField returns the name of the TTL field in the payloads of the negotiated version
+func (p WebhookProvider) ttlField() string {
+	if naming, ok := p.codec().(FieldNaming); ok && naming == FieldNamingSnakeCase {
+		return "record_ttl"
+	}
+	return "recordTTL"
+}
+
+// withZeroTTLs adds a zero TTL to the endpoints of the JSON encoded changes or endpoints lacking one,
+// keeping the order of the payload
+func withZeroTTLs(payload []byte, field string) ([]byte, error) {
+	payload = bytes.TrimSpace(payload)
+	if bytes.HasPrefix(payload, []byte("[")) {
+		list, err := withZeroTTLsInList(payload, field)
+		return append(list, '\n'), err
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString("{")
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(value, []byte("[")) {
+			if value, err = withZeroTTLsInList(value, field); err != nil {
+				return nil, err
+			}
+		}
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		out.Write(k)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+// withZeroTTLsInList adds a zero TTL to the JSON encoded endpoints of list lacking one
+func withZeroTTLsInList(list []byte, field string) ([]byte, error) {
+	var endpoints []json.RawMessage
+	if err := json.Unmarshal(list, &endpoints); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString("[")
+	for i, e := range endpoints {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(e, &fields); err != nil {
+			return nil, err
+		}
+		if _, ok := fields[field]; ok {
+			out.Write(e)
+			continue
+		}
+		e = bytes.TrimSuffix(bytes.TrimSpace(e), []byte("}"))
+		out.Write(e)
+		if len(fields) > 0 {
+			out.WriteByte(',')
+		}
+		fmt.Fprintf(out, "%q:0}", field)
+	}
+	out.WriteString("]")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestZeroTTL(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []WebhookOption
+		payload string
+	}{
+		{
+			name:    "omitted by default",
+			payload: `{"Create":[{"dnsName":"a.example.com","targets":["1.2.3.4"],"recordType":"A"}],"UpdateOld":null,"UpdateNew":null,"Delete":null}`,
+		},
+		{
+			name:    "omitted despite a default TTL",
+			opts:    []WebhookOption{WebhookWithZeroTTL(ZeroTTLOmit), WebhookWithDefaultTTL(300)},
+			payload: `{"Create":[{"dnsName":"a.example.com","targets":["1.2.3.4"],"recordType":"A"}],"UpdateOld":null,"UpdateNew":null,"Delete":null}`,
+		},
+		{
+			name:    "default TTL",
+			opts:    []WebhookOption{WebhookWithZeroTTL(ZeroTTLDefault), WebhookWithDefaultTTL(300)},
+			payload: `{"Create":[{"dnsName":"a.example.com","targets":["1.2.3.4"],"recordType":"A","recordTTL":300}],"UpdateOld":null,"UpdateNew":null,"Delete":null}`,
+		},
+		{
+			name:    "zero",
+			opts:    []WebhookOption{WebhookWithZeroTTL(ZeroTTLSend), WebhookWithDefaultTTL(300)},
+			payload: `{"Create":[{"dnsName":"a.example.com","targets":["1.2.3.4"],"recordType":"A","recordTTL":0}],"UpdateOld":null,"UpdateNew":null,"Delete":null}`,
+		},
+		{
+			name:    "zero snake case",
+			opts:    []WebhookOption{WebhookWithZeroTTL(ZeroTTLSend), WebhookWithFieldNaming(FieldNamingSnakeCase)},
+			payload: `{"create":[{"dns_name":"a.example.com","targets":["1.2.3.4"],"record_type":"A","record_ttl":0}],"update_old":null,"update_new":null,"delete":null}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload string
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+					w.Write([]byte(`{}`))
+					return
+				}
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				payload = string(b)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer svr.Close()
+
+			provider, err := NewWebhookProvider(svr.URL, tc.opts...)
+			require.NoError(t, err)
+			require.NoError(t, provider.ApplyChanges(context.TODO(), &plan.Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+			}))
+			require.JSONEq(t, tc.payload, payload)
+		})
+	}
+}
+
+func TestWithZeroTTLs(t *testing.T) {
+	// the order of the payload is kept and the configured TTLs are left untouched
+	payload, err := withZeroTTLs([]byte(`{"Delete":[{}],"Create":[{"dnsName":"a","recordTTL":60},{"dnsName":"b"}],"UpdateOld":null}`), "recordTTL")
+	require.NoError(t, err)
+	require.Equal(t, `{"Delete":[{"recordTTL":0}],"Create":[{"dnsName":"a","recordTTL":60},{"dnsName":"b","recordTTL":0}],"UpdateOld":null}`+"\n", string(payload))
+
+	payload, err = withZeroTTLs([]byte(`[{"dnsName":"a"}]`+"\n"), "recordTTL")
+	require.NoError(t, err)
+	require.Equal(t, `[{"dnsName":"a","recordTTL":0}]`+"\n", string(payload))
+}
+
+func TestParseZeroTTLMode(t *testing.T) {
+	mode, err := ParseZeroTTLMode("zero")
+	require.NoError(t, err)
+	require.Equal(t, ZeroTTLSend, mode)
+	_, err = ParseZeroTTLMode("none")
+	require.EqualError(t, err, `unknown zero TTL mode "none"`)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// WebhookWithZoneConcurrency groups the changes by zone, as found in the negotiated domain filter,
+// and applies up to limit zones concurrently with one request per zone.
+// The order of the changes within a zone is preserved. A regex domain filter, having no domains, is refused.
+func WebhookWithZoneConcurrency(limit int) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.zoneConcurrency = limit
+	}
+}
+
+// WebhookWithZoneScopedRecords fetches the records of every zone with a separate GET request
+// to /records?zone={zone}, which is cheaper for webhooks managing many zones.
+// Without zones, the domains of the negotiated domain filter are used as zones, and when there are none
+// all the records are fetched at once. A regex domain filter requires explicit zones.
+func WebhookWithZoneScopedRecords(zones ...string) WebhookOption {
+	return func(p *WebhookProvider) {
+		p.zoneScopedRecords = true
+		p.recordZones = zones
+	}
+}
+
+// recordsZones returns the zones whose records are fetched separately
+func (p WebhookProvider) recordsZones() []string {
+	if !p.zoneScopedRecords {
+		return nil
+	}
+	if len(p.recordZones) > 0 {
+		return p.recordZones
+	}
+	zones := make([]string, 0, len(p.DomainFilter.Filters))
+	for _, z := range p.DomainFilter.Filters {
+		if z = strings.TrimPrefix(z, "."); z != "" {
+			zones = append(zones, z)
+		}
+	}
+	return zones
+}
+
+// zoneOf returns the longest domain of the filter matching name, or an empty string if none matches
+func zoneOf(zones []string, name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	zone := ""
+	for _, z := range zones {
+		z = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(z), "."), ".")
+		if z == "" || len(z) <= len(zone) {
+			continue
+		}
+		if name == z || strings.HasSuffix(name, "."+z) {
+			zone = z
+		}
+	}
+	return zone
+}
+
+// changesByZone splits the changes by zone, keeping the relative order of the changes of each zone
+// and the update pairs together
+func changesByZone(zones []string, changes *plan.Changes) map[string]*plan.Changes {
+	byZone := map[string]*plan.Changes{}
+	get := func(e *endpoint.Endpoint) *plan.Changes {
+		z := zoneOf(zones, e.DNSName)
+		if _, ok := byZone[z]; !ok {
+			byZone[z] = &plan.Changes{}
+		}
+		return byZone[z]
+	}
+	for _, e := range changes.Create {
+		c := get(e)
+		c.Create = append(c.Create, e)
+	}
+	for i, e := range changes.UpdateNew {
+		c := get(e)
+		c.UpdateNew = append(c.UpdateNew, e)
+		if i < len(changes.UpdateOld) {
+			c.UpdateOld = append(c.UpdateOld, changes.UpdateOld[i])
+		}
+	}
+	for _, e := range changes.Delete {
+		c := get(e)
+		c.Delete = append(c.Delete, e)
+	}
+	return byZone
+}
+
+// applyChangesPerZone applies the changes of each zone in a separate request, running up to zoneConcurrency
+// requests concurrently, and returns all the errors encountered
+func (p WebhookProvider) applyChangesPerZone(ctx context.Context, changes *plan.Changes) error {
+	byZone := changesByZone(p.DomainFilter.Filters, changes)
+	if len(byZone) <= 1 {
+		return p.applyChanges(ctx, changes, nil)
+	}
+
+	zones := make([]string, 0, len(byZone))
+	for z := range byZone {
+		zones = append(zones, z)
+	}
+	sort.Strings(zones)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, p.zoneConcurrency)
+	for _, z := range zones {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(zone string, c *plan.Changes) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := p.applyChanges(ctx, c, nil); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("zone %q: %w", zone, err))
+				mu.Unlock()
+			}
+		}(z, byZone[z])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestZoneOf(t *testing.T) {
+	zones := []string{"example.com", "sub.example.com", "example.org."}
+	require.Equal(t, "example.com", zoneOf(zones, "a.example.com"))
+	require.Equal(t, "sub.example.com", zoneOf(zones, "a.sub.example.com"))
+	require.Equal(t, "example.org", zoneOf(zones, "example.org."))
+	require.Equal(t, "", zoneOf(zones, "a.example.net"))
+	require.Equal(t, "", zoneOf(zones, "aexample.com"))
+}
+
+func TestChangesByZone(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "2.2.2.2"),
+			endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "2.2.2.2"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("d.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("e.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}
+
+	byZone := changesByZone([]string{"example.com", "example.org"}, changes)
+	require.Len(t, byZone, 2)
+	require.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{changes.Create[0], changes.Create[2]},
+		UpdateOld: []*endpoint.Endpoint{changes.UpdateOld[1]},
+		UpdateNew: []*endpoint.Endpoint{changes.UpdateNew[1]},
+		Delete:    []*endpoint.Endpoint{changes.Delete[0], changes.Delete[1]},
+	}, byZone["example.com"])
+	require.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{changes.Create[1]},
+		UpdateOld: []*endpoint.Endpoint{changes.UpdateOld[0]},
+		UpdateNew: []*endpoint.Endpoint{changes.UpdateNew[0]},
+	}, byZone["example.org"])
+}
+
+// newZoneServer returns a webhook server advertising the given zones and storing the changes received per zone
+func newZoneServer(t testing.TB, zones []string, latency time.Duration, received map[string]*plan.Changes, mu *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			json.NewEncoder(w).Encode(endpoint.NewDomainFilter(zones))
+			return
+		}
+		time.Sleep(latency)
+		changes := &plan.Changes{}
+		if err := json.NewDecoder(r.Body).Decode(changes); err != nil {
+			t.Error(err)
+		}
+		if received != nil {
+			e := changesEndpoints(changes)[0]
+			mu.Lock()
+			received[zoneOf(zones, e.DNSName)] = changes
+			mu.Unlock()
+		}
+		if strings.HasSuffix(changesEndpoints(changes)[0].DNSName, "broken.com") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestApplyChangesPerZone(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]*plan.Changes{}
+	zones := []string{"example.com", "example.org", "broken.com"}
+	svr := newZoneServer(t, zones, 0, received, &mu)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithZoneConcurrency(2))
+	require.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("a.broken.com", endpoint.RecordTypeA, "1.1.1.1"),
+		},
+	}
+	err = provider.ApplyChanges(context.TODO(), changes)
+	require.EqualError(t, err, `zone "broken.com": failed to apply changes with code 500`)
+
+	require.Len(t, received, 3)
+	// deletes and creates of a zone are sent together so that the webhook applies them in order
+	require.Equal(t, "b.example.com", received["example.com"].Delete[0].DNSName)
+	require.Equal(t, "a.example.com", received["example.com"].Create[0].DNSName)
+	require.Equal(t, "a.example.org", received["example.org"].Create[0].DNSName)
+	require.Empty(t, received["example.org"].Delete)
+}
+
+func benchmarkApplyChangesPerZone(b *testing.B, concurrency int) {
+	zones := []string{}
+	changes := &plan.Changes{}
+	for i := 0; i < 10; i++ {
+		zone := fmt.Sprintf("zone%d.example.com", i)
+		zones = append(zones, zone)
+		changes.Create = append(changes.Create, endpoint.NewEndpoint("a."+zone, endpoint.RecordTypeA, "1.1.1.1"))
+		changes.Delete = append(changes.Delete, endpoint.NewEndpoint("b."+zone, endpoint.RecordTypeA, "1.1.1.1"))
+	}
+	svr := newZoneServer(b, zones, 5*time.Millisecond, nil, nil)
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL, WebhookWithZoneConcurrency(concurrency))
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := provider.ApplyChanges(context.TODO(), changes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkApplyChangesPerZoneSequential(b *testing.B) {
+	benchmarkApplyChangesPerZone(b, 1)
+}
+
+func BenchmarkApplyChangesPerZoneConcurrent(b *testing.B) {
+	benchmarkApplyChangesPerZone(b, 10)
+}
+
+func TestZoneScopedRecords(t *testing.T) {
+	var queries []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{"include":["example.com","example.org"]}`))
+			return
+		}
+		zone := r.URL.Query().Get("zone")
+		queries = append(queries, zone)
+		switch zone {
+		case "":
+			w.Write([]byte(`[{"dnsName":"a.example.com","recordType":"A","targets":["1.2.3.4"]},{"dnsName":"a.example.org","recordType":"A","targets":["1.2.3.4"]}]`))
+		default:
+			fmt.Fprintf(w, `[{"dnsName":"a.%s","recordType":"A","targets":["1.2.3.4"]}]`, zone)
+		}
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name    string
+		opts    []WebhookOption
+		queries []string
+		records []string
+	}{
+		{
+			name:    "not scoped",
+			queries: []string{""},
+			records: []string{"a.example.com", "a.example.org"},
+		},
+		{
+			name:    "single zone",
+			opts:    []WebhookOption{WebhookWithZoneScopedRecords("example.com")},
+			queries: []string{"example.com"},
+			records: []string{"a.example.com"},
+		},
+		{
+			name:    "zones of the domain filter",
+			opts:    []WebhookOption{WebhookWithZoneScopedRecords()},
+			queries: []string{"example.com", "example.org"},
+			records: []string{"a.example.com", "a.example.org"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			queries = nil
+			p, err := NewWebhookProvider(svr.URL, tc.opts...)
+			require.NoError(t, err)
+
+			endpoints, err := p.Records(context.TODO())
+			require.NoError(t, err)
+			require.Equal(t, tc.queries, queries)
+			var records []string
+			for _, e := range endpoints {
+				records = append(records, e.DNSName)
+			}
+			require.Equal(t, tc.records, records)
+		})
+	}
+}
+
+func TestZoneScopedRecordsWithoutZones(t *testing.T) {
+	var rawQuery string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(contentTypeHeader, mediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		rawQuery = r.URL.RawQuery
+		w.Write([]byte(`[]`))
+	}))
+	defer svr.Close()
+
+	p, err := NewWebhookProvider(svr.URL, WebhookWithZoneScopedRecords())
+	require.NoError(t, err)
+	_, err = p.Records(context.TODO())
+	require.NoError(t, err)
+	require.Empty(t, rawQuery)
+}
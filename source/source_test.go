@@ -96,3 +96,10 @@ func TestSuitableType(t *testing.T) {
 		}
 	}
 }
+
+func TestGetProviderSpecificWebhookAnnotations(t *testing.T) {
+	providerSpecific, _ := getProviderSpecificAnnotations(map[string]string{
+		"external-dns.alpha.kubernetes.io/webhook-ttl-pinned": "true",
+	})
+	assert.Equal(t, endpoint.ProviderSpecific{{Name: "webhook/ttl-pinned", Value: "true"}}, providerSpecific)
+}